@@ -0,0 +1,415 @@
+package strobemers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AlignScoring contains the scores of pairwise alignment.
+// A gap of length k costs GapOpen + k*GapExtend.
+type AlignScoring struct {
+	Match     int // positive score of a match
+	Mismatch  int // positive penalty of a mismatch
+	GapOpen   int // positive penalty of opening a gap
+	GapExtend int // positive penalty of extending a gap by one base
+}
+
+// CigarOp is an operation of CIGAR.
+type CigarOp struct {
+	Op  byte // M, I, D, or S
+	Len int
+}
+
+// Cigar is a list of CIGAR operations.
+type Cigar []CigarOp
+
+// String returns the CIGAR string, e.g., 10S20M1I30M.
+func (c Cigar) String() string {
+	if len(c) == 0 {
+		return "*"
+	}
+	var b strings.Builder
+	for _, op := range c {
+		b.WriteString(strconv.Itoa(op.Len))
+		b.WriteByte(op.Op)
+	}
+	return b.String()
+}
+
+// QueryLen returns the number of query bases consumed, including soft clips.
+func (c Cigar) QueryLen() int {
+	var n int
+	for _, op := range c {
+		if op.Op != 'D' {
+			n += op.Len
+		}
+	}
+	return n
+}
+
+// RefLen returns the number of reference bases consumed.
+func (c Cigar) RefLen() int {
+	var n int
+	for _, op := range c {
+		if op.Op == 'M' || op.Op == 'D' {
+			n += op.Len
+		}
+	}
+	return n
+}
+
+// add appends an operation, merging it with the last one if possible.
+func (c Cigar) add(op byte, n int) Cigar {
+	if n <= 0 {
+		return c
+	}
+	if len(c) > 0 && c[len(c)-1].Op == op {
+		c[len(c)-1].Len += n
+		return c
+	}
+	return append(c, CigarOp{Op: op, Len: n})
+}
+
+// concat appends all operations of another CIGAR.
+func (c Cigar) concat(d Cigar) Cigar {
+	for _, op := range d {
+		c = c.add(op.Op, op.Len)
+	}
+	return c
+}
+
+// reverse reverses the operations in place.
+func (c Cigar) reverse() Cigar {
+	for i, j := 0, len(c)-1; i < j; i, j = i+1, j-1 {
+		c[i], c[j] = c[j], c[i]
+	}
+	return c
+}
+
+const negInf = -(1 << 30)
+
+// traceback bits of a DP cell
+const (
+	tbDiag   uint8 = 0 // H from the diagonal
+	tbDel    uint8 = 1 // H from E, a deletion (gap in query)
+	tbIns    uint8 = 2 // H from F, an insertion (gap in reference)
+	tbStart  uint8 = 3 // start of a fitting alignment
+	tbMaskH  uint8 = 3
+	tbExtDel uint8 = 1 << 2 // E extended from E of the left cell
+	tbExtIns uint8 = 1 << 3 // F extended from F of the upper cell
+)
+
+// alignResult is the result of alignBanded and alignFit.
+type alignResult struct {
+	score  int
+	rs     int // start position in the reference, only for alignFit
+	qe, re int // end positions in the query and reference
+	cigar  Cigar
+}
+
+// alignBanded aligns q and r with affine gap penalties in a band of
+// diagonals [lo, hi], where the diagonal of cell (i, j) is j - i.
+//
+// In global mode, the alignment ends at (len(q), len(r)).
+// Otherwise it's an extension alignment starting at (0, 0), ending at the
+// cell of maximum score, and rows are not computed any more when their
+// maximum drops below the best score by more than xdrop.
+func alignBanded(q, r []byte, sc *AlignScoring, lo, hi int, global bool, xdrop int) alignResult {
+	m, n := len(q), len(r)
+	w := hi - lo + 1
+	o, e := sc.GapOpen, sc.GapExtend
+
+	hPrev := make([]int, w+1)
+	ePrev := make([]int, w+1)
+	fPrev := make([]int, w+1)
+	hCur := make([]int, w+1)
+	eCur := make([]int, w+1)
+	fCur := make([]int, w+1)
+	tb := make([]uint8, (m+1)*w)
+
+	best, bestI, bestJ := 0, 0, 0
+
+	// row 0
+	var j, k int
+	for k = 0; k <= w; k++ {
+		hPrev[k], ePrev[k], fPrev[k] = negInf, negInf, negInf
+	}
+	for k = 0; k < w; k++ {
+		j = lo + k
+		if j < 0 || j > n {
+			continue
+		}
+		if j == 0 {
+			hPrev[k] = 0
+			continue
+		}
+		ePrev[k] = -(o + e*j)
+		hPrev[k] = ePrev[k]
+		tb[k] = tbDel
+		if j > 1 {
+			tb[k] |= tbExtDel
+		}
+	}
+
+	var h, ev, fv, s, rowMax, rowMaxJ int
+	var t uint8
+	lastRow := 0
+	for i := 1; i <= m; i++ {
+		rowMax, rowMaxJ = negInf, -1
+		for k = 0; k < w; k++ {
+			hCur[k], eCur[k], fCur[k] = negInf, negInf, negInf
+			j = i + lo + k
+			if j < 0 || j > n {
+				continue
+			}
+
+			t = 0
+			// F: insertion, from the upper cell (i-1, j), which is k+1 in the previous row
+			fv = negInf
+			if k+1 < w {
+				fv = hPrev[k+1] - o - e
+				if fPrev[k+1]-e > fv {
+					fv = fPrev[k+1] - e
+					t |= tbExtIns
+				}
+			}
+			if j == 0 {
+				hCur[k], fCur[k] = fv, fv
+				tb[i*w+k] = t | tbIns
+				continue
+			}
+
+			// E: deletion, from the left cell (i, j-1), which is k-1 in the current row
+			ev = negInf
+			if k > 0 {
+				ev = hCur[k-1] - o - e
+				if eCur[k-1]-e > ev {
+					ev = eCur[k-1] - e
+					t |= tbExtDel
+				}
+			}
+
+			// H
+			if q[i-1] == r[j-1] {
+				s = sc.Match
+			} else {
+				s = -sc.Mismatch
+			}
+			h = hPrev[k] + s
+			if hPrev[k] <= negInf {
+				h = negInf
+			}
+			if ev > h {
+				h = ev
+				t |= tbDel
+			}
+			if fv > h {
+				h = fv
+				t = t&^tbMaskH | tbIns
+			}
+
+			hCur[k], eCur[k], fCur[k] = h, ev, fv
+			tb[i*w+k] = t
+
+			if h > rowMax {
+				rowMax, rowMaxJ = h, j
+			}
+		}
+
+		hPrev, hCur = hCur, hPrev
+		ePrev, eCur = eCur, ePrev
+		fPrev, fCur = fCur, fPrev
+		lastRow = i
+
+		if !global {
+			if rowMax > best {
+				best, bestI, bestJ = rowMax, i, rowMaxJ
+			} else if rowMaxJ < 0 || rowMax < best-xdrop {
+				break
+			}
+		}
+	}
+
+	var ei, ej int
+	if global {
+		ei, ej = m, n
+		best = negInf
+		if lastRow == m && n-m >= lo && n-m <= hi {
+			best = hPrev[n-m-lo]
+		}
+	} else {
+		ei, ej = bestI, bestJ
+	}
+
+	// traceback
+	cigar := make(Cigar, 0, 8)
+	state := uint8(0) // 0 for H, 1 for E, 2 for F
+	i := ei
+	j = ej
+	for i > 0 || j > 0 {
+		t = tb[i*w+j-i-lo]
+		switch state {
+		case 0:
+			switch t & tbMaskH {
+			case tbDiag:
+				cigar = cigar.add('M', 1)
+				i--
+				j--
+			case tbDel:
+				state = 1
+			case tbIns:
+				state = 2
+			}
+		case 1:
+			cigar = cigar.add('D', 1)
+			if t&tbExtDel == 0 {
+				state = 0
+			}
+			j--
+		case 2:
+			cigar = cigar.add('I', 1)
+			if t&tbExtIns == 0 {
+				state = 0
+			}
+			i--
+		}
+	}
+
+	return alignResult{score: best, qe: ei, re: ej, cigar: cigar.reverse()}
+}
+
+// alignGlobal aligns two sequences end to end, the band is widened to
+// include the diagonal of the end cell.
+func alignGlobal(q, r []byte, sc *AlignScoring, bandwidth int) alignResult {
+	if len(q) == 0 || len(r) == 0 {
+		cigar := make(Cigar, 0, 1)
+		cigar = cigar.add('I', len(q)).add('D', len(r))
+		var score int
+		if len(q)+len(r) > 0 {
+			score = -(sc.GapOpen + sc.GapExtend*(len(q)+len(r)))
+		}
+		return alignResult{score: score, qe: len(q), re: len(r), cigar: cigar}
+	}
+	d := len(r) - len(q)
+	return alignBanded(q, r, sc, minInt(0, d)-bandwidth, maxInt(0, d)+bandwidth, true, 0)
+}
+
+// alignExtend extends an alignment from the start of q and r with X-drop.
+func alignExtend(q, r []byte, sc *AlignScoring, bandwidth int, xdrop int) alignResult {
+	if len(q) == 0 || len(r) == 0 {
+		return alignResult{}
+	}
+	return alignBanded(q, r, sc, -bandwidth, bandwidth, false, xdrop)
+}
+
+// alignFit aligns the whole q to a substring of r with affine gap penalties,
+// i.e., gaps at both ends of r are free. It's used in rescuing mates of
+// paired-end reads in a small reference window.
+func alignFit(q, r []byte, sc *AlignScoring) alignResult {
+	m, n := len(q), len(r)
+	o, e := sc.GapOpen, sc.GapExtend
+	w := n + 1
+
+	h := make([]int, (m+1)*w)
+	ev := make([]int, w) // E of the current row
+	fv := make([]int, w) // F of the previous and current rows
+	tb := make([]uint8, (m+1)*w)
+
+	var i, j, s, hv, ef, ff int
+	var t uint8
+	for j = 0; j <= n; j++ {
+		h[j], fv[j] = 0, negInf
+		tb[j] = tbStart
+	}
+	for i = 1; i <= m; i++ {
+		ev[0] = negInf
+		for j = 0; j <= n; j++ {
+			t = 0
+
+			ff = h[(i-1)*w+j] - o - e
+			if fv[j]-e > ff {
+				ff = fv[j] - e
+				t |= tbExtIns
+			}
+			fv[j] = ff
+			if j == 0 {
+				h[i*w] = ff
+				tb[i*w] = t | tbIns
+				continue
+			}
+
+			ef = h[i*w+j-1] - o - e
+			if ev[j-1]-e > ef {
+				ef = ev[j-1] - e
+				t |= tbExtDel
+			}
+			ev[j] = ef
+
+			if q[i-1] == r[j-1] {
+				s = sc.Match
+			} else {
+				s = -sc.Mismatch
+			}
+			hv = h[(i-1)*w+j-1] + s
+			if ef > hv {
+				hv = ef
+				t |= tbDel
+			}
+			if ff > hv {
+				hv = ff
+				t = t&^tbMaskH | tbIns
+			}
+			h[i*w+j] = hv
+			tb[i*w+j] = t
+		}
+	}
+
+	// best end in the last row
+	best, bestJ := negInf, 0
+	for j = 0; j <= n; j++ {
+		if h[m*w+j] > best {
+			best, bestJ = h[m*w+j], j
+		}
+	}
+
+	cigar := make(Cigar, 0, 8)
+	state := 0 // 0 for H, 1 for E, 2 for F
+	i, j = m, bestJ
+	for i > 0 {
+		t = tb[i*w+j]
+		switch state {
+		case 0:
+			switch t & tbMaskH {
+			case tbDiag:
+				cigar = cigar.add('M', 1)
+				i--
+				j--
+			case tbDel:
+				state = 1
+			case tbIns:
+				state = 2
+			}
+		case 1:
+			cigar = cigar.add('D', 1)
+			if t&tbExtDel == 0 {
+				state = 0
+			}
+			j--
+		case 2:
+			cigar = cigar.add('I', 1)
+			if t&tbExtIns == 0 {
+				state = 0
+			}
+			i--
+		}
+	}
+
+	return alignResult{score: best, rs: j, qe: m, re: bestJ, cigar: cigar.reverse()}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,90 @@
+package strobemers
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAlignGlobal(t *testing.T) {
+	sc := &DefaultAlignOptions.AlignScoring
+	tests := []struct {
+		q, r  string
+		cigar string
+		score int
+	}{
+		{"ACGTACGT", "ACGTACGT", "8M", 16},
+		{"ACGTACGT", "ACGAACGT", "8M", 14 - 8},
+		{"ACGTTACGT", "ACGTACGT", "3M1I5M", 16 - 14},
+		{"ACGTACGT", "ACGTTTACGT", "3M2D5M", 16 - 16},
+		{"", "ACG", "3D", -18},
+	}
+	for _, test := range tests {
+		res := alignGlobal([]byte(test.q), []byte(test.r), sc, 10)
+		if res.cigar.String() != test.cigar || res.score != test.score {
+			t.Errorf("%s vs %s: expected %s (%d), got %s (%d)",
+				test.q, test.r, test.cigar, test.score, res.cigar, res.score)
+		}
+	}
+}
+
+func TestAlignExtend(t *testing.T) {
+	sc := &DefaultAlignOptions.AlignScoring
+	r := rand.New(rand.NewSource(1))
+	s := randomSeq(r, 100)
+	q := append(append([]byte{}, s[:60]...), randomSeq(r, 40)...)
+
+	res := alignExtend(q, s, sc, 20, 30)
+	if res.qe < 60 || res.qe > 62 || res.cigar.QueryLen() != res.qe || res.cigar.RefLen() != res.re {
+		t.Errorf("unexpected extension: %d, %d, %s", res.qe, res.re, res.cigar)
+	}
+}
+
+func TestAligner(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	ref := randomSeq(r, 5000)
+
+	b, _ := NewIndexBuilder(p)
+	b.Add("ref", ref)
+	mp := NewMapper(b.Build(), &DefaultMapOptions)
+	al, err := NewAligner(mp, [][]byte{ref}, &DefaultAlignOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a substitution, an insertion and a deletion
+	read := make([]byte, 0, 310)
+	read = append(read, ref[1000:1100]...)
+	read = append(read, cbases[ref[1100]])
+	read = append(read, ref[1101:1200]...)
+	read = append(read, 'A', 'C', 'C')
+	read = append(read, ref[1200:1250]...)
+	read = append(read, ref[1252:1300]...)
+
+	for _, rev := range []bool{false, true} {
+		q := read
+		if rev {
+			q = ReverseComplement(read, nil)
+		}
+		alns, err := al.Align("read", q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alns) == 0 {
+			t.Fatalf("read not aligned")
+		}
+		a := alns[0]
+		if a.Rev != rev || a.AlnRStart != 1000 || a.AlnREnd != 1300 {
+			t.Errorf("unexpected alignment: rev %v, [%d, %d)", a.Rev, a.AlnRStart, a.AlnREnd)
+		}
+		if a.NM != 6 || a.Cigar.QueryLen() != len(read) || a.Cigar.RefLen() != a.AlnREnd-a.AlnRStart {
+			t.Errorf("unexpected CIGAR %s, NM: %d", a.Cigar, a.NM)
+		}
+
+		rec := a.SAMRecord(q, nil)
+		if rec.Pos != 1001 || string(rec.Seq) != string(read) || !strings.Contains(strings.Join(rec.Tags, " "), "NM:i:6") {
+			t.Errorf("unexpected SAM record: %+v", rec)
+		}
+	}
+}
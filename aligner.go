@@ -0,0 +1,227 @@
+package strobemers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// AlignOptions contains the parameters of base-level alignment.
+type AlignOptions struct {
+	AlignScoring
+
+	Bandwidth int // band width of the dynamic programming
+	XDrop     int // X-drop of extension at the ends of chains
+}
+
+// DefaultAlignOptions is the default AlignOptions, similar to minimap2 -x sr.
+var DefaultAlignOptions = AlignOptions{
+	AlignScoring: AlignScoring{
+		Match:     2,
+		Mismatch:  8,
+		GapOpen:   12,
+		GapExtend: 2,
+	},
+	Bandwidth: 100,
+	XDrop:     100,
+}
+
+// Alignment is a base-level alignment of a query.
+type Alignment struct {
+	*Mapping
+
+	// Aligned intervals (0-based, half-open), named apart from the chain
+	// intervals of the Mapping. Query positions are on the strand aligned
+	// to the reference, i.e., the reverse complementary sequence for Rev
+	// alignments.
+	AlnQStart, AlnQEnd int
+	AlnRStart, AlnREnd int
+
+	Cigar    Cigar // including soft clips
+	AlnScore int   // alignment score, while Score is the chain score
+	NM       int   // edit distance
+}
+
+// Aligner computes base-level alignments of mappings, the gaps between
+// consecutive strobemer anchors are filled with banded global alignment,
+// and the ends of chains are extended with X-drop.
+// An Aligner should not be used in multiple goroutines.
+type Aligner struct {
+	mp   *Mapper
+	refs [][]byte
+	opt  AlignOptions
+
+	rc []byte // buffer of reverse complementary sequence
+}
+
+// NewAligner creates an Aligner. refs are reference sequences in the same
+// order as they were added to the index.
+func NewAligner(mp *Mapper, refs [][]byte, opt *AlignOptions) (*Aligner, error) {
+	idx := mp.Index()
+	if len(refs) != idx.NumRefs() {
+		return nil, fmt.Errorf("%w: %d sequences given, %d in the index", ErrReferencesMismatch, len(refs), idx.NumRefs())
+	}
+	for i, ref := range refs {
+		if len(ref) != idx.RefLens[i] {
+			return nil, fmt.Errorf("%w: length of %s", ErrReferencesMismatch, idx.RefNames[i])
+		}
+	}
+	return &Aligner{mp: mp, refs: refs, opt: *opt}, nil
+}
+
+// Mapper returns the Mapper of the aligner.
+func (al *Aligner) Mapper() *Mapper {
+	return al.mp
+}
+
+// Align maps and aligns a query, primary alignments come first.
+func (al *Aligner) Align(name string, seq []byte) ([]*Alignment, error) {
+	mappings, err := al.mp.Map(name, seq)
+	if err != nil {
+		return nil, err
+	}
+	alns := make([]*Alignment, 0, len(mappings))
+	for _, m := range mappings {
+		alns = append(alns, al.AlignMapping(m, seq))
+	}
+	return alns, nil
+}
+
+// AlignMapping computes the base-level alignment of a mapping of seq.
+func (al *Aligner) AlignMapping(m *Mapping, seq []byte) *Alignment {
+	q := seq
+	if m.Rev {
+		al.rc = ReverseComplement(seq, al.rc)
+		q = al.rc
+	}
+	r := al.refs[m.Ref]
+	sc := &al.opt.AlignScoring
+
+	blocks := al.matchBlocks(m.Chain, q, r)
+
+	a := &Alignment{Mapping: m}
+	cigar := make(Cigar, 0, 16)
+
+	// left extension, on reversed sequences
+	b := blocks[0]
+	rs := maxInt(0, b.r-b.q-al.opt.Bandwidth)
+	left := alignExtend(reverseBytes(q[:b.q]), reverseBytes(r[rs:b.r]), sc, al.opt.Bandwidth, al.opt.XDrop)
+	a.AlnQStart, a.AlnRStart = b.q-left.qe, b.r-left.re
+	a.AlnScore = left.score
+	cigar = cigar.add('S', a.AlnQStart)
+	cigar = cigar.concat(left.cigar.reverse())
+
+	// blocks and gaps between them
+	var prev block
+	var gap alignResult
+	for i, b := range blocks {
+		if i > 0 {
+			gap = alignGlobal(q[prev.q+prev.n:b.q], r[prev.r+prev.n:b.r], sc, al.opt.Bandwidth)
+			cigar = cigar.concat(gap.cigar)
+			a.AlnScore += gap.score
+		}
+		cigar = cigar.add('M', b.n)
+		a.AlnScore += b.n * sc.Match
+		prev = b
+	}
+
+	// right extension
+	qs, rs := prev.q+prev.n, prev.r+prev.n
+	re := minInt(len(r), rs+len(q)-qs+al.opt.Bandwidth)
+	right := alignExtend(q[qs:], r[rs:re], sc, al.opt.Bandwidth, al.opt.XDrop)
+	a.AlnQEnd, a.AlnREnd = qs+right.qe, rs+right.re
+	a.AlnScore += right.score
+	cigar = cigar.concat(right.cigar)
+	cigar = cigar.add('S', len(q)-a.AlnQEnd)
+
+	a.Cigar = cigar
+	a.NM = editDistance(cigar, q, r[a.AlnRStart:])
+	return a
+}
+
+// block is an exact match of n bases at q and r.
+type block struct {
+	q, r, n int
+}
+
+// matchBlocks returns collinear and non-overlapping exact matches
+// from all strobes of the anchors of a chain.
+func (al *Aligner) matchBlocks(c *Chain, q, r []byte) []block {
+	n, l := al.mp.idx.Params.N, al.mp.idx.Params.L
+	blocks := make([]block, 0, len(c.Anchors)*n)
+	var i int
+	for _, a := range c.Anchors {
+		for i = 0; i < n; i++ {
+			blocks = append(blocks, block{q: a.QPos[i], r: a.RPos[i], n: l})
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].q != blocks[j].q {
+			return blocks[i].q < blocks[j].q
+		}
+		return blocks[i].r < blocks[j].r
+	})
+
+	kept := blocks[:0]
+	var last *block
+	for _, b := range blocks {
+		if !bytes.Equal(q[b.q:b.q+b.n], r[b.r:b.r+b.n]) { // hash collision
+			continue
+		}
+		if len(kept) == 0 {
+			kept = append(kept, b)
+			continue
+		}
+		last = &kept[len(kept)-1]
+		if b.q-b.r == last.q-last.r && b.q <= last.q+last.n { // overlapping on the same diagonal
+			if b.q+b.n > last.q+last.n {
+				last.n = b.q + b.n - last.q
+			}
+			continue
+		}
+		if b.q >= last.q+last.n && b.r >= last.r+last.n {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 { // all are collisions, use the first anchor anyway
+		a := c.Anchors[0]
+		kept = append(kept, block{q: a.QPos[0], r: a.RPos[0], n: 0})
+	}
+	return kept
+}
+
+// editDistance computes the number of mismatches and gap bases,
+// r starts at the first aligned base.
+func editDistance(cigar Cigar, q, r []byte) int {
+	var nm, i, j, k int
+	for _, op := range cigar {
+		switch op.Op {
+		case 'M':
+			for k = 0; k < op.Len; k++ {
+				if q[i+k] != r[j+k] {
+					nm++
+				}
+			}
+			i += op.Len
+			j += op.Len
+		case 'I':
+			nm += op.Len
+			i += op.Len
+		case 'D':
+			nm += op.Len
+			j += op.Len
+		case 'S':
+			i += op.Len
+		}
+	}
+	return nm
+}
+
+func reverseBytes(s []byte) []byte {
+	t := make([]byte, len(s))
+	last := len(s) - 1
+	for i, b := range s {
+		t[last-i] = b
+	}
+	return t
+}
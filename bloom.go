@@ -0,0 +1,266 @@
+package strobemers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// bloomSalts are the odd constants of split-block Bloom filters in Parquet,
+// one for each word of a block.
+var bloomSalts = [8]uint32{
+	0x47b6137b, 0x44974d91, 0x8824ad5b, 0xa2b7289d,
+	0x705495c7, 0x2df1424b, 0x9efc4947, 0x5c6bfb31,
+}
+
+// bloomBlock is a block of 256 bits, fitting in a cache line.
+type bloomBlock [8]uint32
+
+// BloomFilter is a split-block Bloom filter of strobemers for fast
+// membership tests, e.g., screening reads against a reference panel.
+// Each strobemer sets one bit in each of the eight words of a 256-bit
+// block, so a test touches only one cache line. Strobemer hash values are
+// mixed with hash64, so any HashFunc can be used.
+type BloomFilter struct {
+	Params Params
+
+	blocks []bloomBlock
+}
+
+// NewBloomFilter creates an empty BloomFilter for n strobemers with the
+// false positive rate fpr.
+func NewBloomFilter(p *Params, n int, fpr float64) (*BloomFilter, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	if !(fpr > 0 && fpr < 1) {
+		return nil, fmt.Errorf("strobemers: invalid false positive rate: %f", fpr)
+	}
+	// the number of bits, as in Parquet
+	bits := -8 * float64(n) / math.Log(1-math.Pow(fpr, 1.0/8))
+	nBlocks := int(math.Ceil(bits / 256))
+	if nBlocks < 1 {
+		nBlocks = 1
+	}
+	return &BloomFilter{
+		Params: *p,
+		blocks: make([]bloomBlock, nBlocks),
+	}, nil
+}
+
+// Size returns the size of the filter in bytes.
+func (f *BloomFilter) Size() int {
+	return len(f.blocks) * 32
+}
+
+// Add inserts strobemers of the positive strand of a reference sequence.
+// The other strand is not needed, as Query checks both strands of reads.
+func (f *BloomFilter) Add(seq []byte) error {
+	return addStrobemers(seq, &f.Params, false, f.AddHash)
+}
+
+// block returns the block of a mixed hash value and the value for bits.
+func (f *BloomFilter) block(hash uint64) (*bloomBlock, uint32) {
+	h := hash64(hash)
+	i := (h >> 32) * uint64(len(f.blocks)) >> 32
+	return &f.blocks[i], uint32(h)
+}
+
+// AddHash adds a strobemer hash value.
+func (f *BloomFilter) AddHash(hash uint64) {
+	b, x := f.block(hash)
+	for i, salt := range bloomSalts {
+		b[i] |= 1 << ((x * salt) >> 27)
+	}
+}
+
+// Contains tells whether a strobemer hash value might be in the filter.
+func (f *BloomFilter) Contains(hash uint64) bool {
+	b, x := f.block(hash)
+	for i, salt := range bloomSalts {
+		if b[i]&(1<<((x*salt)>>27)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the number of strobemers of a read and how many of them are
+// present in the filter. Both strands are checked, and the one with more
+// strobemers present is reported.
+func (f *BloomFilter) Query(seq []byte) (present int, total int, err error) {
+	present, total, err = f.query(seq)
+	if err != nil {
+		return 0, 0, err
+	}
+	rc := ReverseComplement(seq, nil)
+	present2, total2, err := f.query(rc)
+	if err != nil {
+		return 0, 0, err
+	}
+	if present2 > present {
+		return present2, total2, nil
+	}
+	return present, total, nil
+}
+
+func (f *BloomFilter) query(seq []byte) (present int, total int, err error) {
+	iter, err := newIteratorIfLongEnough(&seq, &f.Params)
+	if err != nil || iter == nil {
+		return 0, 0, err
+	}
+	var hash uint64
+	var ok bool
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+		total++
+		if f.Contains(hash) {
+			present++
+		}
+	}
+	return present, total, nil
+}
+
+// Fraction returns the fraction of strobemers of a read present in the
+// filter, see Query. It returns 0 for reads too short to produce any
+// strobemer.
+func (f *BloomFilter) Fraction(seq []byte) (float64, error) {
+	present, total, err := f.Query(seq)
+	if err != nil || total == 0 {
+		return 0, err
+	}
+	return float64(present) / float64(total), nil
+}
+
+// Merge adds all strobemers of another filter of the same size.
+func (f *BloomFilter) Merge(o *BloomFilter) error {
+	if !f.Params.Equal(&o.Params) || len(f.blocks) != len(o.blocks) {
+		return ErrSketchMismatch
+	}
+	for i := range o.blocks {
+		for j, w := range o.blocks[i] {
+			f.blocks[i][j] |= w
+		}
+	}
+	return nil
+}
+
+// BloomFilter file format (little-endian):
+//
+//	magic          [8]byte   "STROBBLM"
+//	format version uint32
+//	scheme, hash, shrink, sampling 4 x uint8
+//	n, l, wMin, wMax               4 x uint32
+//	prime          uint64
+//	sampleW, syncmerS, syncmerT    3 x uint32
+//	strobe syncmer s               uint32
+//	#blocks        uint64
+//	blocks         #blocks x 8 x uint32
+
+// BloomFilterFormatVersion is the version of the BloomFilter file format.
+const BloomFilterFormatVersion uint32 = 1
+
+var bloomFilterMagic = [8]byte{'S', 'T', 'R', 'O', 'B', 'B', 'L', 'M'}
+
+// maxBloomBlocks limits the filter size (64 GB) when reading a corrupted file.
+const maxBloomBlocks = 1 << 31
+
+// bloomReadChunk is the number of blocks (32 MB) allocated at a time when
+// reading a filter.
+const bloomReadChunk = 1 << 20
+
+// WriteTo writes the filter in the binary format.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriterSize(w, 1<<16)
+	cw := &countingWriter{w: bw}
+
+	var err error
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(cw, binary.LittleEndian, data)
+		}
+	}
+	write(bloomFilterMagic)
+	write(BloomFilterFormatVersion)
+	write(newParamsHeader(&f.Params))
+	write(uint64(len(f.blocks)))
+	if err != nil {
+		return cw.n, err
+	}
+
+	buf := make([]byte, 32)
+	for i := range f.blocks {
+		for j, v := range f.blocks[i] {
+			binary.LittleEndian.PutUint32(buf[j<<2:], v)
+		}
+		if _, err = cw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, bw.Flush()
+}
+
+// ReadBloomFilter reads a filter written by WriteTo. The number of blocks in
+// the header is checked against the size of r if it's an io.Seeker, and
+// blocks are allocated while reading, so a corrupted header can not cause
+// a huge allocation.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	size := remainingSize(r)
+	br := bufio.NewReaderSize(r, 1<<16)
+
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(br, binary.LittleEndian, data)
+		}
+	}
+	var magic [8]byte
+	var version uint32
+	var hdr paramsHeader
+	var n uint64
+	read(&magic)
+	read(&version)
+	read(&hdr)
+	read(&n)
+	if err != nil {
+		return nil, sketchReadError(err)
+	}
+	if magic != bloomFilterMagic {
+		return nil, fmt.Errorf("%w: bad magic number", ErrInvalidSketchFile)
+	}
+	if version != BloomFilterFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version: %d", ErrInvalidSketchFile, version)
+	}
+	if n == 0 || n > maxBloomBlocks {
+		return nil, fmt.Errorf("%w: invalid number of blocks: %d", ErrInvalidSketchFile, n)
+	}
+	if size >= 0 && int64(n)*32 > size-int64(len(magic)+4+binary.Size(hdr)+8) {
+		return nil, fmt.Errorf("%w: unexpected file size", ErrInvalidSketchFile)
+	}
+	p := hdr.params()
+	if err = p.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSketchFile, err)
+	}
+
+	f := &BloomFilter{Params: p, blocks: make([]bloomBlock, 0, minUint64(n, bloomReadChunk))}
+	buf := make([]byte, 32)
+	var b bloomBlock
+	for i := uint64(0); i < n; i++ {
+		if _, err = io.ReadFull(br, buf); err != nil {
+			return nil, sketchReadError(err)
+		}
+		for j := range b {
+			b[j] = binary.LittleEndian.Uint32(buf[j<<2:])
+		}
+		f.blocks = append(f.blocks, b)
+	}
+	return f, nil
+}
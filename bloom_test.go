@@ -0,0 +1,102 @@
+package strobemers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	r := rand.New(rand.NewSource(47))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	ref := randomSeq(r, 100000)
+
+	fpr := 0.01
+	f, err := NewBloomFilter(p, len(ref), fpr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Add(ref); err != nil {
+		t.Fatal(err)
+	}
+
+	// no false negatives
+	for _, sm := range collectStrobemers(t, ref, p) {
+		if !f.Contains(sm.hash) {
+			t.Fatalf("false negative")
+		}
+	}
+
+	// false positives
+	var fp int
+	for i := 0; i < 100000; i++ {
+		if f.Contains(r.Uint64()) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 100000; rate > 2*fpr {
+		t.Errorf("false positive rate too high: %f", rate)
+	}
+
+	// reads from both strands of the reference, and random reads
+	read := append([]byte{}, ref[5000:6000]...)
+	for _, seq := range [][]byte{read, ReverseComplement(read, nil)} {
+		frac, err := f.Fraction(seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frac < 0.95 {
+			t.Errorf("unexpected fraction of a read from the reference: %f", frac)
+		}
+	}
+	if frac, _ := f.Fraction(randomSeq(r, 1000)); frac > 0.05 {
+		t.Errorf("unexpected fraction of a random read: %f", frac)
+	}
+	if frac, err := f.Fraction([]byte("ACGT")); frac != 0 || err != nil {
+		t.Errorf("short reads should have no strobemers")
+	}
+
+	// serialization
+	buf := &bytes.Buffer{}
+	if _, err = f.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != f.Size()+8+4+44+8 {
+		t.Errorf("unexpected file size: %d", buf.Len())
+	}
+	g, err := ReadBloomFilter(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, g) {
+		t.Errorf("filter changed after serialization")
+	}
+	if _, err = ReadBloomFilter(bytes.NewReader(buf.Bytes()[:100])); err == nil {
+		t.Errorf("truncated file should be rejected")
+	}
+	g, err = ReadBloomFilter(struct{ io.Reader }{bytes.NewReader(buf.Bytes())})
+	if err != nil || !reflect.DeepEqual(f, g) {
+		t.Errorf("filter changed after reading from a stream")
+	}
+
+	// a huge number of blocks in the header, with and without the input size
+	data := append([]byte{}, buf.Bytes()...)
+	binary.LittleEndian.PutUint64(data[8+4+44:], maxBloomBlocks)
+	if _, err = ReadBloomFilter(bytes.NewReader(data)); !errors.Is(err, ErrInvalidSketchFile) {
+		t.Errorf("expected ErrInvalidSketchFile, got: %v", err)
+	}
+	_, err = ReadBloomFilter(struct{ io.Reader }{bytes.NewReader(data)})
+	if !errors.Is(err, ErrInvalidSketchFile) {
+		t.Errorf("expected ErrInvalidSketchFile, got: %v", err)
+	}
+
+	// merge
+	h, _ := NewBloomFilter(p, len(ref), fpr)
+	if err = h.Merge(f); err != nil || !reflect.DeepEqual(f.blocks, h.blocks) {
+		t.Errorf("unexpected merge result")
+	}
+}
@@ -0,0 +1,345 @@
+package strobemers
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// BottomK is a bottom-k MinHash sketch of strobemers, keeping the k
+// smallest values of each hash function. Hash functions are seeded,
+// i.e., hash64(h ^ seed) of strobemer hash values h, so any HashFunc
+// can be used.
+type BottomK struct {
+	Params Params
+	K      int
+	Seeds  []uint64 // seeds of hash functions
+
+	heaps []*maxHeap // one for each hash function
+	sets  []map[uint64]struct{}
+}
+
+// maxHeap is a max-heap of uint64.
+type maxHeap []uint64
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// NewBottomK creates an empty BottomK sketch of size k. One hash function
+// with the seed of 0 is used if no seeds are given.
+func NewBottomK(p *Params, k int, seeds ...uint64) (*BottomK, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if k < 1 {
+		return nil, fmt.Errorf("strobemers: invalid sketch size: %d", k)
+	}
+	if len(seeds) == 0 {
+		seeds = []uint64{0}
+	}
+	s := &BottomK{
+		Params: *p,
+		K:      k,
+		Seeds:  append([]uint64{}, seeds...),
+		heaps:  make([]*maxHeap, len(seeds)),
+		sets:   make([]map[uint64]struct{}, len(seeds)),
+	}
+	// heaps and sets grow as values are added, as k may be far more than
+	// the number of strobemers
+	n := minInt(k, 1024)
+	for i := range seeds {
+		h := make(maxHeap, 0, n)
+		s.heaps[i] = &h
+		s.sets[i] = make(map[uint64]struct{}, n)
+	}
+	return s, nil
+}
+
+// Add adds strobemers of the positive strand of seq to every hash function.
+// Use AddBothStrands for sequences whose orientation is unknown, as
+// strobemers of the two strands differ.
+func (s *BottomK) Add(seq []byte) error {
+	return addStrobemers(seq, &s.Params, false, s.AddHash)
+}
+
+// AddBothStrands adds strobemers of seq and of its reverse complement.
+func (s *BottomK) AddBothStrands(seq []byte) error {
+	return addStrobemers(seq, &s.Params, true, s.AddHash)
+}
+
+// AddHash adds a strobemer hash value.
+func (s *BottomK) AddHash(hash uint64) {
+	for i, seed := range s.Seeds {
+		s.add(i, hash64(hash^seed))
+	}
+}
+
+// add adds a value of the i-th hash function.
+func (s *BottomK) add(i int, v uint64) {
+	h, set := s.heaps[i], s.sets[i]
+	if _, ok := set[v]; ok {
+		return
+	}
+	if len(*h) < s.K {
+		heap.Push(h, v)
+		set[v] = struct{}{}
+		return
+	}
+	if v >= (*h)[0] {
+		return
+	}
+	delete(set, (*h)[0])
+	(*h)[0] = v
+	heap.Fix(h, 0)
+	set[v] = struct{}{}
+}
+
+// Values returns sorted values of the i-th hash function.
+func (s *BottomK) Values(i int) []uint64 {
+	values := append([]uint64{}, *s.heaps[i]...)
+	sort.Slice(values, func(a, b int) bool { return values[a] < values[b] })
+	return values
+}
+
+// Len returns the number of values of each hash function.
+func (s *BottomK) Len() int {
+	return len(*s.heaps[0])
+}
+
+func (s *BottomK) check(o *BottomK) error {
+	if !s.Params.Equal(&o.Params) || s.K != o.K || len(s.Seeds) != len(o.Seeds) {
+		return ErrSketchMismatch
+	}
+	for i, seed := range s.Seeds {
+		if seed != o.Seeds[i] {
+			return ErrSketchMismatch
+		}
+	}
+	return nil
+}
+
+// Merge merges another sketch, the result is the sketch of the union.
+func (s *BottomK) Merge(o *BottomK) error {
+	if err := s.check(o); err != nil {
+		return err
+	}
+	for i, h := range o.heaps {
+		for _, v := range *h {
+			s.add(i, v)
+		}
+	}
+	return nil
+}
+
+// Jaccard estimates the Jaccard index, averaged over hash functions.
+// For each one, the k smallest values of the union are computed from
+// the two sketches, and the fraction of them found in both is returned.
+func (s *BottomK) Jaccard(o *BottomK) (float64, error) {
+	if err := s.check(o); err != nil {
+		return 0, err
+	}
+	var sum float64
+	for i := range s.heaps {
+		a, b := s.Values(i), o.Values(i)
+		var x, y, n, common int
+		for n < s.K && (x < len(a) || y < len(b)) {
+			switch {
+			case y == len(b) || (x < len(a) && a[x] < b[y]):
+				x++
+			case x == len(a) || b[y] < a[x]:
+				y++
+			default:
+				common++
+				x++
+				y++
+			}
+			n++
+		}
+		if n > 0 {
+			sum += float64(common) / float64(n)
+		}
+	}
+	return sum / float64(len(s.heaps)), nil
+}
+
+// StdError returns the standard error of a Jaccard index j estimated from
+// the sketch, i.e., sqrt(j(1-j)/(k*m)), where m is the number of hash
+// functions.
+func (s *BottomK) StdError(j float64) float64 {
+	return math.Sqrt(j * (1 - j) / float64(s.K*len(s.Seeds)))
+}
+
+// Cardinality estimates the number of distinct strobemers from the k-th
+// smallest value of the first hash function, i.e., (k-1) / (v_k/2^64).
+// The exact number is returned if the sketch is not full.
+func (s *BottomK) Cardinality() float64 {
+	h := *s.heaps[0]
+	if len(h) < s.K || s.K < 2 {
+		return float64(len(h))
+	}
+	return float64(s.K-1) / (float64(h[0]) / math.MaxUint64)
+}
+
+// BottomK file format (little-endian):
+//
+//	magic          [8]byte   "STROBBTK"
+//	format version uint32
+//	scheme, hash, shrink, sampling 4 x uint8
+//	n, l, wMin, wMax               4 x uint32
+//	prime          uint64
+//	sampleW, syncmerS, syncmerT    3 x uint32
+//	strobe syncmer s               uint32
+//	k              uint32
+//	#functions     uint32
+//	seeds          #functions x uint64
+//	for each function:
+//	  #values      uint32
+//	  values       sorted, delta-encoded uvarints
+
+// BottomKFormatVersion is the version of the BottomK file format.
+const BottomKFormatVersion uint32 = 2
+
+var bottomKMagic = [8]byte{'S', 'T', 'R', 'O', 'B', 'B', 'T', 'K'}
+
+// WriteTo writes the sketch in the compact binary format.
+func (s *BottomK) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	var err error
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(cw, binary.LittleEndian, data)
+		}
+	}
+	write(bottomKMagic)
+	write(BottomKFormatVersion)
+	write(newParamsHeader(&s.Params))
+	write(uint32(s.K))
+	write(uint32(len(s.Seeds)))
+	write(s.Seeds)
+	if err != nil {
+		return cw.n, err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	var prev uint64
+	var n int
+	for i := range s.heaps {
+		values := s.Values(i)
+		write(uint32(len(values)))
+		prev = 0
+		for _, v := range values {
+			n = binary.PutUvarint(buf, v-prev)
+			if err == nil {
+				_, err = cw.Write(buf[:n])
+			}
+			prev = v
+		}
+	}
+	if err != nil {
+		return cw.n, err
+	}
+	return cw.n, bw.Flush()
+}
+
+// ReadBottomK reads a sketch written by WriteTo. If r is an io.Seeker,
+// e.g., a file, the numbers of seeds and values are also checked against
+// its size.
+func ReadBottomK(r io.Reader) (*BottomK, error) {
+	size := remainingSize(r)
+	br := bufio.NewReader(r)
+
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(br, binary.LittleEndian, data)
+		}
+	}
+	var magic [8]byte
+	var version, k, nFuncs uint32
+	var hdr paramsHeader
+	read(&magic)
+	read(&version)
+	read(&hdr)
+	read(&k)
+	read(&nFuncs)
+	if err != nil {
+		return nil, sketchReadError(err)
+	}
+	if magic != bottomKMagic {
+		return nil, fmt.Errorf("%w: bad magic number", ErrInvalidSketchFile)
+	}
+	if version != BottomKFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version: %d", ErrInvalidSketchFile, version)
+	}
+	if nFuncs == 0 || nFuncs > 1<<16 || k > 1<<30 {
+		return nil, fmt.Errorf("%w: invalid sketch size", ErrInvalidSketchFile)
+	}
+	// the number of bytes left after the header, -1 for unknown. Every
+	// function takes at least 12 bytes for the seed and #values, and every
+	// value at least one byte.
+	left := int64(-1)
+	if size >= 0 {
+		left = size - int64(len(magic)+4+binary.Size(hdr)+8) - int64(nFuncs)*8
+		if left < int64(nFuncs)*4 {
+			return nil, fmt.Errorf("%w: unexpected file size", ErrInvalidSketchFile)
+		}
+	}
+	seeds := make([]uint64, nFuncs)
+	read(seeds)
+	if err != nil {
+		return nil, sketchReadError(err)
+	}
+
+	p := hdr.params()
+	s, err := NewBottomK(&p, int(k), seeds...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSketchFile, err)
+	}
+
+	var n uint32
+	var v, d uint64
+	buf := make([]byte, binary.MaxVarintLen64)
+	for i := range s.heaps {
+		read(&n)
+		if err != nil {
+			return nil, sketchReadError(err)
+		}
+		if n > k {
+			return nil, fmt.Errorf("%w: too many values", ErrInvalidSketchFile)
+		}
+		if left >= 0 {
+			left -= 4
+			if int64(n)+int64(len(s.heaps)-i-1)*4 > left {
+				return nil, fmt.Errorf("%w: unexpected file size", ErrInvalidSketchFile)
+			}
+		}
+		v = 0
+		for j := uint32(0); j < n; j++ {
+			d, err = binary.ReadUvarint(br)
+			if err != nil {
+				return nil, sketchReadError(err)
+			}
+			if left >= 0 {
+				left -= int64(binary.PutUvarint(buf, d))
+			}
+			v += d
+			s.add(i, v)
+		}
+	}
+	return s, nil
+}
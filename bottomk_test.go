@@ -0,0 +1,152 @@
+package strobemers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBottomK(t *testing.T) {
+	r := rand.New(rand.NewSource(31))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	seqA := randomSeq(r, 100000)
+	seqB := append(append([]byte{}, seqA[:60000]...), randomSeq(r, 40000)...)
+	setA, _ := NewHashSet([][]byte{seqA}, p)
+	setB, _ := NewHashSet([][]byte{seqB}, p)
+	real := Jaccard(setA, setB)
+
+	k := 1000
+	seeds := []uint64{1, 2, 3, 4}
+	a, err := NewBottomK(p, k, seeds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewBottomK(p, k, seeds...)
+	a.Add(seqA)
+	b.Add(seqB)
+	if a.Len() != k {
+		t.Errorf("unexpected sketch size: %d", a.Len())
+	}
+	values := a.Values(0)
+	for i := 1; i < len(values); i++ {
+		if values[i] <= values[i-1] {
+			t.Fatalf("values are not sorted or not distinct")
+		}
+	}
+
+	j, err := a.Jaccard(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := a.StdError(j)
+	if math.Abs(j-real) > 4*se {
+		t.Errorf("estimated Jaccard %.4f ± %.4f, real %.4f", j, se, real)
+	}
+	if c := a.Cardinality(); math.Abs(c-float64(len(setA)))/float64(len(setA)) > 0.1 {
+		t.Errorf("estimated cardinality %.0f, real %d", c, len(setA))
+	}
+
+	// merging sketches of parts equals the sketch of the whole
+	m1, _ := NewBottomK(p, k, seeds...)
+	m2, _ := NewBottomK(p, k, seeds...)
+	set1, _ := NewHashSet([][]byte{seqA[:50000]}, p)
+	set2, _ := NewHashSet([][]byte{seqA[50000:]}, p)
+	for h := range set1 {
+		m1.AddHash(h)
+	}
+	for h := range set2 {
+		m2.AddHash(h)
+	}
+	m1.Merge(m2)
+	whole, _ := NewBottomK(p, k, seeds...)
+	for h := range set1 {
+		whole.AddHash(h)
+	}
+	for h := range set2 {
+		whole.AddHash(h)
+	}
+	for i := range seeds {
+		if !reflect.DeepEqual(m1.Values(i), whole.Values(i)) {
+			t.Errorf("merged sketch differs from the sketch of the union")
+		}
+	}
+
+	// both strands
+	both, _ := NewBottomK(p, k, seeds...)
+	both.AddBothStrands(seqA)
+	m1, _ = NewBottomK(p, k, seeds...)
+	m1.Add(ReverseComplement(seqA, nil))
+	m1.Merge(a)
+	for i := range seeds {
+		if !reflect.DeepEqual(both.Values(i), m1.Values(i)) {
+			t.Errorf("unexpected sketch of both strands")
+		}
+	}
+
+	other, _ := NewBottomK(p, k, 5)
+	if _, err = a.Jaccard(other); err != ErrSketchMismatch {
+		t.Errorf("sketches of different seeds should not be compared")
+	}
+
+	// serialization
+	buf := &bytes.Buffer{}
+	if _, err = a.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= k*len(seeds)*8 {
+		t.Errorf("serialized sketch is not compact: %d bytes", buf.Len())
+	}
+	a2, err := ReadBottomK(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a2.Params.Equal(&a.Params) || a2.K != a.K || !reflect.DeepEqual(a2.Seeds, a.Seeds) {
+		t.Errorf("sketch parameters changed after serialization")
+	}
+	for i := range seeds {
+		if !reflect.DeepEqual(a2.Values(i), a.Values(i)) {
+			t.Errorf("sketch values changed after serialization")
+		}
+	}
+	if _, err = ReadBottomK(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Errorf("truncated file should be rejected")
+	}
+
+	// forged headers, read from a file-like reader and a stream
+	forge := func(k, nFuncs, nValues uint32) []byte {
+		data := append([]byte{}, buf.Bytes()...)
+		i := 8 + 4 + 44
+		binary.LittleEndian.PutUint32(data[i:], k)
+		binary.LittleEndian.PutUint32(data[i+4:], nFuncs)
+		if nValues > 0 {
+			binary.LittleEndian.PutUint32(data[i+8+len(seeds)*8:], nValues)
+		}
+		return data
+	}
+	for _, data := range [][]byte{
+		forge(1<<30, 1<<16, 0),   // a huge k and too many functions
+		forge(1<<30, 4, 1<<20),   // more values than the rest of the file
+		forge(1<<30, 4, 1<<30+1), // more values than k
+	} {
+		if _, err = ReadBottomK(bytes.NewReader(data)); !errors.Is(err, ErrInvalidSketchFile) {
+			t.Errorf("expected ErrInvalidSketchFile, got: %v", err)
+		}
+		if _, err = ReadBottomK(struct{ io.Reader }{bytes.NewReader(data)}); !errors.Is(err, ErrInvalidSketchFile) {
+			t.Errorf("expected ErrInvalidSketchFile from a stream, got: %v", err)
+		}
+	}
+	// a huge k alone is valid, and nothing is allocated for it
+	a3, err := ReadBottomK(struct{ io.Reader }{bytes.NewReader(forge(1<<30, 4, 0))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a3.K != 1<<30 || !reflect.DeepEqual(a3.Values(0), a.Values(0)) || cap(*a3.heaps[0]) > 2*a.Len() {
+		t.Errorf("unexpected sketch with a huge k")
+	}
+}
@@ -0,0 +1,366 @@
+package strobemers
+
+import (
+	"math"
+	"sort"
+)
+
+// ChainOptions contains the parameters of anchor chaining.
+type ChainOptions struct {
+	MaxGap    int // maximum distance between two consecutive anchors on the query or reference
+	Bandwidth int // maximum difference between the query and reference distances
+	MaxIter   int // maximum number of predecessors examined for each anchor
+
+	GapLinear float64 // linear gap cost per base of the distance difference
+	GapLog    float64 // gap cost of log2(distance difference)
+	RepWeight float64 // weight of bases covered by repetitive anchors, in [0, 1]
+
+	MinScore   float64 // minimum chain score
+	MinAnchors int     // minimum number of anchors in a chain
+}
+
+// DefaultChainOptions is the default ChainOptions, similar to that of minimap2.
+var DefaultChainOptions = ChainOptions{
+	MaxGap:    5000,
+	Bandwidth: 500,
+	MaxIter:   50,
+
+	GapLinear: 0.2,
+	GapLog:    0.5,
+	RepWeight: 0.5,
+
+	MinScore:   40,
+	MinAnchors: 2,
+}
+
+// Chain is a list of collinear anchors on the same reference and strand.
+type Chain struct {
+	Ref   uint32
+	Rev   bool
+	Score float64
+
+	// Anchors are sorted by positions.
+	Anchors []Anchor
+
+	// Half-open intervals covered by all strobes of the anchors.
+	// Query positions are on the reverse complementary sequence for Rev chains.
+	QStart, QEnd int
+	RStart, REnd int
+
+	// QCovered is the number of query bases covered by strobes.
+	QCovered int
+}
+
+// interval is a half-open interval.
+type interval struct {
+	start, end int
+}
+
+// strobeIntervals returns the sorted and merged intervals of strobes,
+// it returns the number of intervals.
+func strobeIntervals(pos *[3]int, n int, l int, ivs *[3]interval) int {
+	var m int
+	for i := 0; i < n; i++ {
+		ivs[m] = interval{pos[i], pos[i] + l}
+		// insertion sort
+		for j := m; j > 0 && ivs[j].start < ivs[j-1].start; j-- {
+			ivs[j], ivs[j-1] = ivs[j-1], ivs[j]
+		}
+		m++
+	}
+	// merge overlapping strobes
+	k := 0
+	for i := 1; i < m; i++ {
+		if ivs[i].start <= ivs[k].end {
+			if ivs[i].end > ivs[k].end {
+				ivs[k].end = ivs[i].end
+			}
+			continue
+		}
+		k++
+		ivs[k] = ivs[i]
+	}
+	return k + 1
+}
+
+// covered returns the total length of intervals.
+func covered(ivs *[3]interval, n int) int {
+	var c int
+	for i := 0; i < n; i++ {
+		c += ivs[i].end - ivs[i].start
+	}
+	return c
+}
+
+// overlap returns the overlapped length of two lists of disjoint intervals.
+func overlap(a *[3]interval, na int, b *[3]interval, nb int) int {
+	var o, s, e int
+	for i := 0; i < na; i++ {
+		for j := 0; j < nb; j++ {
+			s, e = a[i].start, a[i].end
+			if b[j].start > s {
+				s = b[j].start
+			}
+			if b[j].end < e {
+				e = b[j].end
+			}
+			if e > s {
+				o += e - s
+			}
+		}
+	}
+	return o
+}
+
+// anchorsByRefPos sorts anchors by reference, strand and then positions on
+// the reference and the query.
+type anchorsByRefPos []Anchor
+
+func (l anchorsByRefPos) Len() int { return len(l) }
+func (l anchorsByRefPos) Less(i int, j int) bool {
+	a, b := &l[i], &l[j]
+	if a.Ref != b.Ref {
+		return a.Ref < b.Ref
+	}
+	if a.Rev != b.Rev {
+		return !a.Rev
+	}
+	if a.RPos[0] != b.RPos[0] {
+		return a.RPos[0] < b.RPos[0]
+	}
+	return a.QPos[0] < b.QPos[0]
+}
+func (l anchorsByRefPos) Swap(i int, j int) { l[i], l[j] = l[j], l[i] }
+
+// ChainAnchors chains collinear anchors with a minimap2-style dynamic programming.
+//
+// Anchors are grouped by reference and strand and sorted by reference
+// positions, as in minimap2, instead of diagonals, because predecessors of
+// an anchor are searched backwards on the reference. This does not affect
+// the dynamic programming: anchors on other diagonals in between are skipped
+// by the bandwidth check without counting towards MaxIter, so anchors on
+// the same diagonal are still chained, and diagonal changes are penalized
+// by the gap cost. In the dynamic programming, the score of appending an anchor to a chain
+// is the number of bases covered by all its strobes (not only the first one)
+// but not by the predecessor, on both the query and the reference, minus the
+// gap cost of the distance difference. Chains are then rescored with the
+// exact number of bases covered by all strobes of their anchors, and
+// returned in decreasing order of scores.
+//
+// The anchors are sorted in place, and l is the strobe length,
+// n is the strobemer order.
+func ChainAnchors(anchors []Anchor, n int, l int, opt *ChainOptions) []*Chain {
+	if len(anchors) == 0 {
+		return nil
+	}
+	sort.Sort(anchorsByRefPos(anchors))
+
+	chains := make([]*Chain, 0, 8)
+
+	var s, e int
+	for s = 0; s < len(anchors); s = e {
+		for e = s + 1; e < len(anchors); e++ {
+			if anchors[e].Ref != anchors[s].Ref || anchors[e].Rev != anchors[s].Rev {
+				break
+			}
+		}
+		chains = append(chains, chainGroup(anchors[s:e], n, l, opt)...)
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool { return chains[i].Score > chains[j].Score })
+	return chains
+}
+
+// chainGroup chains anchors of the same reference and strand.
+func chainGroup(anchors []Anchor, n int, l int, opt *ChainOptions) []*Chain {
+	na := len(anchors)
+	f := make([]float64, na) // best scores of chains ending at anchors
+	p := make([]int, na)     // predecessors
+	qivs := make([][3]interval, na)
+	rivs := make([][3]interval, na)
+	nq := make([]int, na)
+	nr := make([]int, na)
+
+	var a, b *Anchor
+	var dq, dr, gap, iter, gainQ, gainR int
+	var w, score, gain, cost float64
+	for i := range anchors {
+		a = &anchors[i]
+		nq[i] = strobeIntervals(&a.QPos, n, l, &qivs[i])
+		nr[i] = strobeIntervals(&a.RPos, n, l, &rivs[i])
+
+		w = 1
+		if a.Rep {
+			w = opt.RepWeight
+		}
+
+		gain = w * float64(minInt(covered(&qivs[i], nq[i]), covered(&rivs[i], nr[i])))
+		f[i], p[i] = gain, -1
+
+		iter = 0
+		for j := i - 1; j >= 0 && iter < opt.MaxIter; j-- {
+			b = &anchors[j]
+			dr = a.RPos[0] - b.RPos[0]
+			if dr > opt.MaxGap {
+				break
+			}
+			dq = a.QPos[0] - b.QPos[0]
+			if dr <= 0 || dq <= 0 || dq > opt.MaxGap {
+				continue
+			}
+			gap = dr - dq
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > opt.Bandwidth {
+				continue
+			}
+			iter++
+
+			gainQ = covered(&qivs[i], nq[i]) - overlap(&qivs[i], nq[i], &qivs[j], nq[j])
+			gainR = covered(&rivs[i], nr[i]) - overlap(&rivs[i], nr[i], &rivs[j], nr[j])
+			cost = 0
+			if gap > 0 {
+				cost = opt.GapLinear*float64(gap) + opt.GapLog*math.Log2(float64(gap))
+			}
+			score = f[j] + w*float64(minInt(gainQ, gainR)) - cost
+			if score > f[i] {
+				f[i], p[i] = score, j
+			}
+		}
+	}
+
+	// backtrack from the best ends, anchors are used only once.
+	order := make([]int, na)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return f[order[i]] > f[order[j]] })
+
+	used := make([]bool, na)
+	chains := make([]*Chain, 0, 4)
+	var k, m int
+	var end float64 // score of the anchor where backtracking stops
+	for _, i := range order {
+		if used[i] {
+			continue
+		}
+		m = 0
+		end = 0
+		for k = i; k >= 0; k = p[k] {
+			if used[k] {
+				end = f[k]
+				break
+			}
+			m++
+		}
+		if m < opt.MinAnchors || f[i]-end < opt.MinScore {
+			// still mark them used, they belong to a branch of a better chain
+			for k = i; k >= 0 && !used[k]; k = p[k] {
+				used[k] = true
+			}
+			continue
+		}
+
+		c := &Chain{
+			Ref:     anchors[i].Ref,
+			Rev:     anchors[i].Rev,
+			Anchors: make([]Anchor, m),
+		}
+		for k = i; k >= 0 && !used[k]; k = p[k] {
+			used[k] = true
+			m--
+			c.Anchors[m] = anchors[k]
+		}
+		c.rescore(n, l, opt)
+		if c.Score < opt.MinScore {
+			continue
+		}
+		chains = append(chains, c)
+	}
+
+	return chains
+}
+
+// rescore computes the intervals, covered bases, and the exact chain score:
+// the number of bases covered by all strobes, with bases covered only by
+// repetitive anchors down-weighted, minus gap costs of consecutive anchors.
+func (c *Chain) rescore(n int, l int, opt *ChainOptions) {
+	c.QStart, c.RStart = math.MaxInt32, math.MaxInt32
+	c.QEnd, c.REnd = 0, 0
+
+	qAll := make([]interval, 0, len(c.Anchors)*n)
+	rAll := make([]interval, 0, len(c.Anchors)*n)
+	qUniq := make([]interval, 0, len(c.Anchors)*n)
+	rUniq := make([]interval, 0, len(c.Anchors)*n)
+	var ivs [3]interval
+	var m int
+	for i := range c.Anchors {
+		a := &c.Anchors[i]
+		m = strobeIntervals(&a.QPos, n, l, &ivs)
+		qAll = append(qAll, ivs[:m]...)
+		if !a.Rep {
+			qUniq = append(qUniq, ivs[:m]...)
+		}
+		if ivs[0].start < c.QStart {
+			c.QStart = ivs[0].start
+		}
+		if ivs[m-1].end > c.QEnd {
+			c.QEnd = ivs[m-1].end
+		}
+
+		m = strobeIntervals(&a.RPos, n, l, &ivs)
+		rAll = append(rAll, ivs[:m]...)
+		if !a.Rep {
+			rUniq = append(rUniq, ivs[:m]...)
+		}
+		if ivs[0].start < c.RStart {
+			c.RStart = ivs[0].start
+		}
+		if ivs[m-1].end > c.REnd {
+			c.REnd = ivs[m-1].end
+		}
+	}
+	c.QCovered = unionLength(qAll)
+
+	nq, nr := unionLength(qUniq), unionLength(rUniq)
+	wq := float64(nq) + opt.RepWeight*float64(c.QCovered-nq)
+	wr := float64(nr) + opt.RepWeight*float64(unionLength(rAll)-nr)
+	c.Score = math.Min(wq, wr)
+
+	var gap int
+	for i := 1; i < len(c.Anchors); i++ {
+		gap = (c.Anchors[i].RPos[0] - c.Anchors[i-1].RPos[0]) -
+			(c.Anchors[i].QPos[0] - c.Anchors[i-1].QPos[0])
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > 0 {
+			c.Score -= opt.GapLinear*float64(gap) + opt.GapLog*math.Log2(float64(gap))
+		}
+	}
+}
+
+// unionLength returns the number of positions covered by intervals,
+// which are sorted in place.
+func unionLength(ivs []interval) int {
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start < ivs[j].start })
+	var n int
+	var s, e int = -1, -1
+	for _, iv := range ivs {
+		if iv.start > e {
+			n += e - s
+			s, e = iv.start, iv.end
+		} else if iv.end > e {
+			e = iv.end
+		}
+	}
+	return n + e - s
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
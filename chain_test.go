@@ -0,0 +1,72 @@
+package strobemers
+
+import (
+	"testing"
+)
+
+func TestChainAnchors(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	idx := buildTestIndex(t, p)
+
+	start, end := 300, 700
+	query := make([]byte, end-start)
+	copy(query, seqs[0][start:end])
+	query[200] = cbases[query[200]] // a substitution
+
+	for _, rev := range []bool{false, true} {
+		q := query
+		if rev {
+			q = ReverseComplement(query, nil)
+		}
+		anchors, err := idx.Query(q, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chains := ChainAnchors(anchors, p.N, p.L, &DefaultChainOptions)
+		if len(chains) == 0 {
+			t.Fatalf("no chains found")
+		}
+
+		c := chains[0]
+		if c.Ref != 0 || c.Rev != rev {
+			t.Errorf("unexpected best chain: ref %d, rev %v", c.Ref, c.Rev)
+		}
+		if c.RStart < start || c.REnd > end || c.REnd-c.RStart < (end-start)*3/4 {
+			t.Errorf("unexpected reference interval: [%d, %d)", c.RStart, c.REnd)
+		}
+		if c.QEnd-c.QStart != c.REnd-c.RStart {
+			t.Errorf("query and reference intervals should have the same length without indels")
+		}
+		if c.QCovered > c.QEnd-c.QStart || c.Score > float64(c.QCovered) {
+			t.Errorf("unexpected coverage %d or score %.2f", c.QCovered, c.Score)
+		}
+		for i := 1; i < len(c.Anchors); i++ {
+			if c.Anchors[i].RPos[0] <= c.Anchors[i-1].RPos[0] || c.Anchors[i].QPos[0] <= c.Anchors[i-1].QPos[0] {
+				t.Errorf("anchors not collinear")
+				break
+			}
+		}
+	}
+}
+
+func TestChainAnchorsSameDiagonal(t *testing.T) {
+	anchor := func(q, r int) Anchor {
+		return Anchor{QPos: [3]int{q, q + 25}, RPos: [3]int{r, r + 25}}
+	}
+	// two anchors on the diagonal 1000, with anchors on other diagonals
+	// between them in the order of reference positions
+	a, b := anchor(100, 1100), anchor(200, 1200)
+	anchors := []Anchor{b, anchor(50, 1150), a, anchor(900, 1120)}
+
+	chains := ChainAnchors(anchors, _n2, _l2, &DefaultChainOptions)
+	if len(chains) != 1 {
+		t.Fatalf("expected 1 chain, got %d", len(chains))
+	}
+	c := chains[0]
+	if len(c.Anchors) != 2 || c.Anchors[0] != a || c.Anchors[1] != b {
+		t.Errorf("unexpected anchors: %v", c.Anchors)
+	}
+	if c.Score != 60 || c.QStart != 100 || c.QEnd != 240 || c.RStart != 1100 || c.REnd != 1240 {
+		t.Errorf("unexpected chain: %+v", c)
+	}
+}
@@ -0,0 +1,280 @@
+package strobemers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ClusterOptions contains the parameters of read clustering.
+type ClusterOptions struct {
+	// Minimum fraction of strobemers of a read found in a representative
+	// to assign the read to its cluster.
+	MinContainment float64
+	// Keep strobemers with mixed hash values below 2^64/Scale in sketches
+	// of reads, 1 for all strobemers.
+	Scale uint64
+	// Minimum number of shared strobemers with a representative.
+	MinShared int
+	// Number of reads looked up in parallel.
+	BatchSize int
+	// Number of goroutines, 0 for all CPUs.
+	Threads int
+}
+
+// DefaultClusterOptions is the default ClusterOptions.
+var DefaultClusterOptions = ClusterOptions{
+	MinContainment: 0.2,
+	Scale:          1,
+	MinShared:      2,
+	BatchSize:      1024,
+	Threads:        0,
+}
+
+// ClusterAssignment is the cluster of a read.
+type ClusterAssignment struct {
+	Read           int     // index of the read
+	Cluster        int     // 0-based cluster ID, in order of creation
+	Representative int     // index of the representative read
+	Containment    float64 // fraction of the read's strobemers in the representative, 1 for representatives
+	Rev            bool    // the read is reverse complementary to the representative
+}
+
+// Clusters is the result of read clustering.
+type Clusters struct {
+	Names       []string
+	Assignments []ClusterAssignment // in the order of reads
+
+	Representatives []int // indexes of representative reads of clusters
+}
+
+// readSketch is the sketch of both strands of a read.
+type readSketch [2][]uint64
+
+// ClusterReads clusters reads greedily. Reads are processed from the
+// longest to the shortest, and each one is assigned to the representative
+// with the highest containment, i.e., the fraction of strobemers of the
+// read found in the representative, if it's not below MinContainment.
+// Otherwise, the read becomes the representative of a new cluster.
+//
+// Representatives are indexed by strobemers of the positive strand, while
+// both strands of reads are looked up, in parallel in batches.
+func ClusterReads(names []string, seqs [][]byte, p *Params, opt *ClusterOptions) (*Clusters, error) {
+	if len(names) != len(seqs) {
+		return nil, fmt.Errorf("strobemers: %d names given for %d sequences", len(names), len(seqs))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if opt == nil {
+		opt = &DefaultClusterOptions
+	}
+	threads := opt.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	batchSize := opt.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultClusterOptions.BatchSize
+	}
+	scale := opt.Scale
+	if scale < 1 {
+		scale = 1
+	}
+
+	// sketches of all reads
+	sketches := make([]readSketch, len(seqs))
+	jobs := make(chan int, len(seqs))
+	for i := range seqs {
+		jobs <- i
+	}
+	close(jobs)
+	errs := make(chan error, threads)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			for i := range jobs {
+				sketches[i], err = sketchRead(seqs[i], p, math.MaxUint64/scale)
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	// from the longest to the shortest
+	order := make([]int, len(seqs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(seqs[order[i]]) > len(seqs[order[j]]) })
+
+	c := &Clusters{
+		Names:       names,
+		Assignments: make([]ClusterAssignment, len(seqs)),
+	}
+	index := make(map[uint64][]int32, 1024) // strobemer -> clusters
+	var repSets []map[uint64]struct{}       // strobemers of representatives
+
+	best := make([]ClusterAssignment, batchSize)
+	for b := 0; b < len(order); b += batchSize {
+		batch := order[b:minInt(b+batchSize, len(order))]
+
+		// look up clusters created before the batch in parallel
+		nClusters := len(repSets)
+		reads := make(chan int, len(batch))
+		for i := range batch {
+			reads <- i
+		}
+		close(reads)
+		for t := 0; t < threads; t++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				counts := make(map[int32]int, 64)
+				for i := range reads {
+					best[i] = lookupClusters(&sketches[batch[i]], index, counts, opt)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// then clusters created in the batch, one read after another
+		for i, r := range batch {
+			a := best[i]
+			for cl := nClusters; cl < len(repSets); cl++ {
+				for s := 0; s < 2; s++ {
+					shared := 0
+					for _, h := range sketches[r][s] {
+						if _, ok := repSets[cl][h]; ok {
+							shared++
+						}
+					}
+					updateAssignment(&a, int32(cl), s == 1, shared, len(sketches[r][s]), opt)
+				}
+			}
+
+			if a.Cluster < 0 {
+				a = ClusterAssignment{Cluster: len(repSets), Containment: 1}
+				set := make(map[uint64]struct{}, len(sketches[r][0]))
+				for _, h := range sketches[r][0] {
+					set[h] = struct{}{}
+					index[h] = append(index[h], int32(a.Cluster))
+				}
+				repSets = append(repSets, set)
+				c.Representatives = append(c.Representatives, r)
+			}
+			a.Read = r
+			a.Representative = c.Representatives[a.Cluster]
+			c.Assignments[r] = a
+		}
+	}
+	return c, nil
+}
+
+// sketchRead returns sorted and deduplicated strobemers of both strands
+// of a read, with mixed hash values not above max.
+func sketchRead(seq []byte, p *Params, max uint64) (readSketch, error) {
+	var s readSketch
+	rc := ReverseComplement(seq, nil)
+	for i, sq := range [][]byte{seq, rc} {
+		iter, err := newIteratorIfLongEnough(&sq, p)
+		if err != nil {
+			return s, err
+		}
+		if iter == nil {
+			return s, nil
+		}
+		set := make(map[uint64]struct{}, len(sq))
+		var hash uint64
+		var ok bool
+		for {
+			hash, ok = iter.Next()
+			if !ok {
+				break
+			}
+			if hash64(hash) <= max {
+				set[hash] = struct{}{}
+			}
+		}
+		hashes := make([]uint64, 0, len(set))
+		for h := range set {
+			hashes = append(hashes, h)
+		}
+		sort.Slice(hashes, func(a, b int) bool { return hashes[a] < hashes[b] })
+		s[i] = hashes
+	}
+	return s, nil
+}
+
+// lookupClusters returns the best cluster of a read in the inverted index,
+// or a ClusterAssignment with a negative Cluster.
+func lookupClusters(s *readSketch, index map[uint64][]int32, counts map[int32]int,
+	opt *ClusterOptions) ClusterAssignment {
+	a := ClusterAssignment{Cluster: -1}
+	for strand := 0; strand < 2; strand++ {
+		for k := range counts {
+			delete(counts, k)
+		}
+		for _, h := range s[strand] {
+			for _, cl := range index[h] {
+				counts[cl]++
+			}
+		}
+		for cl, n := range counts {
+			updateAssignment(&a, cl, strand == 1, n, len(s[strand]), opt)
+		}
+	}
+	return a
+}
+
+// updateAssignment replaces the assignment with a better cluster. Ties are
+// broken by the smaller cluster ID, i.e., the longer representative.
+func updateAssignment(a *ClusterAssignment, cl int32, rev bool, shared int, total int,
+	opt *ClusterOptions) {
+	if total == 0 || shared < opt.MinShared {
+		return
+	}
+	c := float64(shared) / float64(total)
+	if c < opt.MinContainment {
+		return
+	}
+	if a.Cluster < 0 || c > a.Containment || (c == a.Containment && int(cl) < a.Cluster) {
+		a.Cluster, a.Containment, a.Rev = int(cl), c, rev
+	}
+}
+
+// NumClusters returns the number of clusters.
+func (c *Clusters) NumClusters() int {
+	return len(c.Representatives)
+}
+
+// WriteTSV writes cluster assignments of reads in input order, with the
+// columns: read, cluster, representative, containment and strand.
+func (c *Clusters) WriteTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "read\tcluster\trepresentative\tcontainment\tstrand\n")
+	var strand byte
+	for _, a := range c.Assignments {
+		strand = '+'
+		if a.Rev {
+			strand = '-'
+		}
+		fmt.Fprintf(bw, "%s\t%d\t%s\t%.4f\t%c\n",
+			c.Names[a.Read], a.Cluster, c.Names[a.Representative], a.Containment, strand)
+	}
+	return bw.Flush()
+}
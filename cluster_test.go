@@ -0,0 +1,81 @@
+package strobemers
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClusterReads(t *testing.T) {
+	r := rand.New(rand.NewSource(49))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	// amplicons of 6 sources, with 1% substitutions, trimmed ends and random strands
+	var names []string
+	var seqs [][]byte
+	var sources []int
+	for s := 0; s < 6; s++ {
+		src := randomSeq(r, 1000+r.Intn(1000))
+		for i := 0; i < 8; i++ {
+			read := mutate(r, src, 0.01)
+			read = read[r.Intn(50) : len(read)-r.Intn(50)]
+			if r.Intn(2) == 0 {
+				read = ReverseComplement(read, nil)
+			}
+			names = append(names, fmt.Sprintf("s%d_r%d", s, i))
+			seqs = append(seqs, read)
+			sources = append(sources, s)
+		}
+	}
+	names = append(names, "short")
+	seqs = append(seqs, []byte("ACGTACGT"))
+	sources = append(sources, 6)
+
+	opt := DefaultClusterOptions
+	opt.Threads = 1
+	c, err := ClusterReads(names, seqs, p, &opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NumClusters() != 7 {
+		t.Errorf("unexpected number of clusters: %d", c.NumClusters())
+	}
+	clusterOf := make(map[int]int, 7) // source -> cluster
+	for i, a := range c.Assignments {
+		if a.Read != i {
+			t.Fatalf("assignments not in the order of reads")
+		}
+		if cl, ok := clusterOf[sources[i]]; ok && cl != a.Cluster {
+			t.Errorf("read %s in cluster %d, expected %d", names[i], a.Cluster, cl)
+		}
+		clusterOf[sources[i]] = a.Cluster
+		if len(seqs[a.Representative]) < len(seqs[i]) {
+			t.Errorf("representative shorter than the read %s", names[i])
+		}
+		if a.Representative != i && a.Containment < opt.MinContainment {
+			t.Errorf("unexpected containment of %s: %f", names[i], a.Containment)
+		}
+	}
+
+	// the same result with multiple threads and small batches
+	opt.Threads, opt.BatchSize = 4, 3
+	c2, err := ClusterReads(names, seqs, p, &opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, c2) {
+		t.Errorf("results differ with multiple threads")
+	}
+
+	buf := &bytes.Buffer{}
+	if err = c.WriteTSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(seqs)+1 || !strings.HasPrefix(lines[0], "read\tcluster") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
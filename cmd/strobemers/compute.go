@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/strobemers"
+)
+
+func runCompute(args []string) error {
+	fs := flag.NewFlagSet("compute", flag.ExitOnError)
+
+	scheme := fs.String("scheme", "randstrobes", "strobemer scheme: randstrobes or minstrobes")
+	n := fs.Int("n", 2, "strobemer order, 2 or 3")
+	l := fs.Int("l", 15, "strobe length")
+	wMin := fs.Int("w-min", 20, "minimum window offset")
+	wMax := fs.Int("w-max", 30, "maximum window offset")
+	noShrink := fs.Bool("no-shrink", false, "do not shrink the last window near the end of sequences")
+	hash := fs.String("hash", "ntHash", "hash function: ntHash or ntHash-mixed")
+
+	sampling := fs.String("sampling", "none", "sampling of first strobes: none, minimizer, open-syncmer, closed-syncmer or modulo")
+	sampleW := fs.Int("sample-w", 0, "window size of minimizers, or the modulus for -sampling")
+	syncmerS := fs.Int("syncmer-s", 0, "s-mer length of syncmers for -sampling")
+	syncmerT := fs.Int("syncmer-t", 0, "offset of the smallest s-mer of open syncmers for -sampling")
+	strobeSyncmerS := fs.Int("strobe-syncmer-s", 0, "select the second and third strobes among closed syncmers with s-mers of this length, 0 for all l-mers")
+
+	strand := fs.String("strand", "both", "strand of sequences: both, + or -")
+	threads := fs.Int("j", runtime.NumCPU(), "number of threads")
+	outFile := fs.String("o", "-", `output file, "-" for stdout, gzipped for a ".gz" suffix`)
+	noHeader := fs.Bool("H", false, "do not output the header line")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `usage: %s compute [options] [files]
+
+Compute strobemers of sequences in plain or gzipped FASTA/FASTQ files, "-" or
+no files for stdin. Output is tab-delimited, one row per strobemer, in the order
+of sequences and strobemers:
+
+  seqID  strand  positions  hash
+
+Positions are 0-based start positions of strobes on the positive strand,
+separated by commas.
+
+options:
+`, os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p := strobemers.NewParams(0, *n, *l, *wMin, *wMax)
+	var err error
+	if p.Scheme, err = parseScheme(*scheme); err != nil {
+		return err
+	}
+	if p.Hash, err = parseHashFunc(*hash); err != nil {
+		return err
+	}
+	if p.Sampling, err = parseSampling(*sampling); err != nil {
+		return err
+	}
+	p.Shrink = !*noShrink
+	p.SampleW, p.SyncmerS, p.SyncmerT = *sampleW, *syncmerS, *syncmerT
+	p.StrobeSyncmerS = *strobeSyncmerS
+	if err = p.Validate(); err != nil {
+		return err
+	}
+
+	var strands []bool // whether reverse complementary
+	switch *strand {
+	case "both":
+		strands = []bool{false, true}
+	case "+":
+		strands = []bool{false}
+	case "-":
+		strands = []bool{true}
+	default:
+		return fmt.Errorf("invalid strand: %s", *strand)
+	}
+	if *threads < 1 {
+		*threads = 1
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var w io.Writer = os.Stdout
+	if *outFile != "-" {
+		fh, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		w = fh
+	}
+	bw := bufio.NewWriterSize(w, 1<<16)
+	var out io.Writer = bw
+	var gw *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(*outFile), ".gz") {
+		gw = gzip.NewWriter(bw)
+		out = gw
+	}
+
+	if !*noHeader {
+		if _, err = io.WriteString(out, "seqID\tstrand\tpositions\thash\n"); err != nil {
+			return err
+		}
+	}
+	if err = compute(files, p, strands, *threads, out); err != nil {
+		return err
+	}
+	if gw != nil {
+		if err = gw.Close(); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func parseScheme(s string) (strobemers.Scheme, error) {
+	for _, v := range []strobemers.Scheme{strobemers.SchemeRandStrobes, strobemers.SchemeMinStrobes} {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid scheme: %s", s)
+}
+
+func parseHashFunc(s string) (strobemers.HashFunc, error) {
+	for _, v := range []strobemers.HashFunc{strobemers.HashNtHash, strobemers.HashNtHashMixed} {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid hash function: %s", s)
+}
+
+func parseSampling(s string) (strobemers.Sampling, error) {
+	for _, v := range []strobemers.Sampling{strobemers.SamplingNone, strobemers.SamplingMinimizer,
+		strobemers.SamplingOpenSyncmer, strobemers.SamplingClosedSyncmer, strobemers.SamplingModulo} {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid sampling method: %s", s)
+}
+
+// Output rows of a sequence are sent to the writer in blocks, so the memory
+// of output in flight is about threads*(outBlocks+1) blocks, no matter how
+// long sequences are.
+const (
+	outBlockSize = 1 << 20
+	outBlocks    = 4
+
+	// the maximum size of a row without the sequence ID
+	maxRowTail = 3 + 3*21 + 21
+)
+
+// job is a sequence and blocks of its output rows.
+type job struct {
+	name []byte
+	seq  []byte
+
+	out chan []byte // closed after all rows are sent
+	err error       // set before out is closed
+}
+
+// compute computes strobemers of sequences in parallel, and writes rows in
+// the order of sequences.
+func compute(files []string, p *strobemers.Params, strands []bool, threads int, w io.Writer) error {
+	jobs := make(chan *job, threads)
+	queue := make(chan *job, threads<<2) // jobs in the order of sequences
+
+	// Workers take jobs in the same order as the writer, so the job being
+	// written is always running or done, while others wait for free blocks.
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var rc []byte
+			for j := range jobs {
+				rc, j.err = strobemerRows(j, p, strands, rc)
+				close(j.out)
+			}
+		}()
+	}
+
+	// writer
+	done := make(chan error)
+	go func() {
+		var err error
+		for j := range queue {
+			for block := range j.out {
+				if err == nil {
+					_, err = w.Write(block)
+				}
+			}
+			if err == nil {
+				err = j.err
+			}
+		}
+		done <- err
+	}()
+
+	// reader
+	var err error
+	for _, file := range files {
+		if err = readFile(file, jobs, queue); err != nil {
+			break
+		}
+	}
+	close(jobs)
+	close(queue)
+	wg.Wait()
+	if werr := <-done; err == nil {
+		err = werr
+	}
+	return err
+}
+
+func readFile(file string, jobs chan *job, queue chan *job) error {
+	reader, err := fastx.NewDefaultReader(file)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var r *fastx.Record
+	var j *job
+	for {
+		r, err = reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("%s: %s", file, err)
+		}
+		j = &job{
+			name: append([]byte{}, r.ID...),
+			seq:  append([]byte{}, r.Seq.Seq...),
+			out:  make(chan []byte, outBlocks),
+		}
+		queue <- j
+		jobs <- j
+	}
+}
+
+// strobemerRows sends the rows of strobemers of a sequence in blocks. The
+// buffer of reverse complementary sequences is returned for reuse.
+func strobemerRows(j *job, p *strobemers.Params, strands []bool, rc []byte) ([]byte, error) {
+	if len(j.seq) < p.N*p.L {
+		return rc, nil
+	}
+	var out []byte
+	qlen := len(j.seq)
+	for _, rev := range strands {
+		seq := j.seq
+		if rev {
+			rc = strobemers.ReverseComplement(j.seq, rc)
+			seq = rc
+		}
+		iter, err := strobemers.NewIterator(&seq, p)
+		if err == strobemers.ErrSequenceTooShort {
+			return rc, nil
+		}
+		if err != nil {
+			return rc, fmt.Errorf("%s: %s", j.name, err)
+		}
+
+		var hash uint64
+		var ok bool
+		var pos int
+		var idxs []int
+		for {
+			hash, ok = iter.Next()
+			if !ok {
+				break
+			}
+			idxs = iter.Indexes()
+
+			if len(out)+len(j.name)+maxRowTail > cap(out) {
+				if len(out) > 0 {
+					j.out <- out
+				}
+				out = make([]byte, 0, outBlockSize)
+			}
+			out = append(out, j.name...)
+			if rev {
+				out = append(out, "\t-\t"...)
+			} else {
+				out = append(out, "\t+\t"...)
+			}
+			for i := 0; i < p.N; i++ {
+				if i > 0 {
+					out = append(out, ',')
+				}
+				pos = idxs[i]
+				if rev {
+					pos = strobemers.QueryPosForward(pos, qlen, p.L)
+				}
+				out = strconv.AppendInt(out, int64(pos), 10)
+			}
+			out = append(out, '\t')
+			out = strconv.AppendUint(out, hash, 10)
+			out = append(out, '\n')
+		}
+	}
+	if len(out) > 0 {
+		j.out <- out
+	}
+	return rc, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shenwei356/strobemers"
+)
+
+type testRecord struct {
+	id  string
+	seq []byte
+}
+
+func testRecords() []testRecord {
+	r := rand.New(rand.NewSource(1))
+	randomSeq := func(n int) []byte {
+		s := make([]byte, n)
+		for i := range s {
+			s[i] = "ACGT"[r.Intn(4)]
+		}
+		return s
+	}
+	// a long sequence with output of several blocks, a sequence too short
+	// for any strobemer, and many short ones
+	records := []testRecord{
+		{"long", randomSeq(100000)},
+		{"short", randomSeq(20)},
+	}
+	for i := 0; i < 50; i++ {
+		records = append(records, testRecord{fmt.Sprintf("seq%d", i), randomSeq(50 + r.Intn(2000))})
+	}
+	return records
+}
+
+func writeTestFiles(t *testing.T, records []testRecord) (fasta, fastq, fastaGz string) {
+	dir := t.TempDir()
+	var fa, fq bytes.Buffer
+	for _, rec := range records {
+		fmt.Fprintf(&fa, ">%s description\n", rec.id)
+		for i := 0; i < len(rec.seq); i += 60 {
+			end := i + 60
+			if end > len(rec.seq) {
+				end = len(rec.seq)
+			}
+			fmt.Fprintf(&fa, "%s\n", rec.seq[i:end])
+		}
+		fmt.Fprintf(&fq, "@%s description\n%s\n+\n%s\n", rec.id, rec.seq, strings.Repeat("I", len(rec.seq)))
+	}
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(fa.Bytes())
+	gw.Close()
+
+	fasta = filepath.Join(dir, "seqs.fa")
+	fastq = filepath.Join(dir, "seqs.fq")
+	fastaGz = filepath.Join(dir, "seqs.fa.gz")
+	for file, data := range map[string][]byte{fasta: fa.Bytes(), fastq: fq.Bytes(), fastaGz: gz.Bytes()} {
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fasta, fastq, fastaGz
+}
+
+// expectedRows computes rows of both strands without the pipeline, and
+// checks that strobes of the negative strand are reverse complementary to
+// the ones at the mapped positions on the positive strand.
+func expectedRows(t *testing.T, records []testRecord, p *strobemers.Params) []byte {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		if len(rec.seq) < p.N*p.L {
+			continue
+		}
+		rc := strobemers.ReverseComplement(rec.seq, nil)
+		for _, s := range []struct {
+			strand string
+			seq    []byte
+		}{{"+", rec.seq}, {"-", rc}} {
+			seq := s.seq
+			iter, err := strobemers.NewIterator(&seq, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for {
+				hash, ok := iter.Next()
+				if !ok {
+					break
+				}
+				positions := make([]string, p.N)
+				for i, idx := range iter.Indexes()[:p.N] {
+					pos := idx
+					if s.strand == "-" {
+						pos = len(rec.seq) - idx - p.L
+						strobe := strobemers.ReverseComplement(rec.seq[pos:pos+p.L], nil)
+						if !bytes.Equal(strobe, rc[idx:idx+p.L]) {
+							t.Fatalf("%s: strobe at %d on the negative strand is not mapped to %d", rec.id, idx, pos)
+						}
+					}
+					positions[i] = strconv.Itoa(pos)
+				}
+				fmt.Fprintf(&buf, "%s\t%s\t%s\t%d\n", rec.id, s.strand, strings.Join(positions, ","), hash)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestCompute(t *testing.T) {
+	records := testRecords()
+	fasta, fastq, fastaGz := writeTestFiles(t, records)
+	strands := []bool{false, true}
+
+	p2 := strobemers.NewParams(strobemers.SchemeRandStrobes, 2, 15, 20, 30)
+	p3 := strobemers.NewParams(strobemers.SchemeMinStrobes, 3, 10, 20, 30)
+	for _, p := range []*strobemers.Params{p2, p3} {
+		expected := expectedRows(t, records, p)
+		if len(expected) < 4*outBlockSize {
+			t.Fatalf("output of %d bytes is too small to test blocks", len(expected))
+		}
+
+		for _, file := range []string{fasta, fastq, fastaGz} {
+			for _, threads := range []int{1, 8} {
+				var buf bytes.Buffer
+				if err := compute([]string{file}, p, strands, threads, &buf); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), expected) {
+					t.Errorf("%s, %s, %d threads: unexpected output", p, filepath.Base(file), threads)
+				}
+			}
+		}
+	}
+
+	// multiple files, in the order of files
+	var buf bytes.Buffer
+	if err := compute([]string{fastq, fasta}, p2, strands, 8, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := expectedRows(t, records, p2)
+	if !bytes.Equal(buf.Bytes(), append(append([]byte{}, expected...), expected...)) {
+		t.Errorf("unexpected output of multiple files")
+	}
+}
+
+func TestComputeRowFormat(t *testing.T) {
+	records := testRecords()[:4]
+	fasta, _, _ := writeTestFiles(t, records)
+	lens := make(map[string]int, len(records))
+	for _, rec := range records {
+		lens[rec.id] = len(rec.seq)
+	}
+	p := strobemers.NewParams(strobemers.SchemeRandStrobes, 3, 10, 20, 30)
+
+	for _, strands := range [][]bool{{false}, {true}, {false, true}} {
+		var buf bytes.Buffer
+		if err := compute([]string{fasta}, p, strands, 4, &buf); err != nil {
+			t.Fatal(err)
+		}
+		nStrands := make(map[string]int, 2)
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 4 {
+				t.Fatalf("expected 4 columns: %q", line)
+			}
+			qlen, ok := lens[fields[0]]
+			if !ok {
+				t.Fatalf("unexpected sequence ID: %q", line)
+			}
+			if fields[1] != "+" && fields[1] != "-" {
+				t.Fatalf("unexpected strand: %q", line)
+			}
+			nStrands[fields[1]]++
+			positions := strings.Split(fields[2], ",")
+			if len(positions) != p.N {
+				t.Fatalf("expected %d positions: %q", p.N, line)
+			}
+			for _, v := range positions {
+				pos, err := strconv.Atoi(v)
+				if err != nil || pos < 0 || pos > qlen-p.L {
+					t.Fatalf("invalid position: %q", line)
+				}
+			}
+			if _, err := strconv.ParseUint(fields[3], 10, 64); err != nil {
+				t.Fatalf("invalid hash: %q", line)
+			}
+		}
+		if len(nStrands) != len(strands) {
+			t.Errorf("expected rows of %d strands, got %v", len(strands), nStrands)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Command strobemers computes strobemers of DNA sequences in FASTA/FASTQ
+// files, for using strobemers in shell pipelines.
+//
+// Usage:
+//
+//	strobemers compute [options] [seqs.fa.gz ...]
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shenwei356/strobemers"
+)
+
+func usage() {
+	name := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, `%s - strobemers of DNA sequences, version %s
+
+usage: %s <command> [options] [files]
+
+commands:
+  compute    compute strobemers of sequences in FASTA/FASTQ files
+  version    print the version
+
+Run "%s <command> -h" for options of a command.
+`, name, strobemers.Version, name, name)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	switch os.Args[1] {
+	case "compute":
+		checkError(runCompute(os.Args[2:]))
+	case "version":
+		fmt.Println(strobemers.Version)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(1)
+	}
+}
+
+func checkError(e error) {
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", e)
+		os.Exit(1)
+	}
+}
@@ -37,6 +37,59 @@ var ErrIncompleteHashValues = fmt.Errorf("strobemers: incomplete hash values")
 
 var ErrPrimeNumberTooSmall = fmt.Errorf("strobemers: the primer number is too small")
 
+// ErrUnknownScheme means the strobemer scheme is not supported
+var ErrUnknownScheme = fmt.Errorf("strobemers: unknown strobemer scheme")
+
+// ErrUnknownHashFunc means the hash function is not supported
+var ErrUnknownHashFunc = fmt.Errorf("strobemers: unknown hash function")
+
+// ErrParamsMismatch means the parameters of an index do not match the query
+var ErrParamsMismatch = fmt.Errorf("strobemers: parameters mismatch")
+
+// ErrInvalidIndexFile means the file is not a valid strobemer index file
+var ErrInvalidIndexFile = fmt.Errorf("strobemers: invalid index file")
+
+// ErrIndexVersionMismatch means the index file format is not supported
+var ErrIndexVersionMismatch = fmt.Errorf("strobemers: index file format version mismatch")
+
+// ErrTooManyReferences means the number of references exceeds the limit
+var ErrTooManyReferences = fmt.Errorf("strobemers: too many references")
+
+// ErrIndexFiltered means strobemers were removed from an index by a hard
+// RepeatFilter, so the index can not be updated or merged exactly.
+var ErrIndexFiltered = fmt.Errorf("strobemers: index with repetitive strobemers removed can not be updated")
+
+// ErrReferencesMismatch means the reference sequences do not match the index
+var ErrReferencesMismatch = fmt.Errorf("strobemers: reference sequences do not match the index")
+
+// ErrSequenceTooLong means the sequence is too long to be indexed
+var ErrSequenceTooLong = fmt.Errorf("strobemers: sequence too long")
+
+// ErrHashNotUniform means the hash values are not uniformly distributed,
+// HashNtHashMixed should be used.
+var ErrHashNotUniform = fmt.Errorf("strobemers: hash values not uniformly distributed, please use HashNtHashMixed")
+
+// ErrSketchMismatch means the sketches are built with different parameters
+var ErrSketchMismatch = fmt.Errorf("strobemers: sketches with different parameters")
+
+// ErrInvalidSketchFile means the file is not a valid sketch file
+var ErrInvalidSketchFile = fmt.Errorf("strobemers: invalid sketch file")
+
+// ErrInvalidSetFile means the file is not a valid strobemer set file
+var ErrInvalidSetFile = fmt.Errorf("strobemers: invalid set file")
+
+// ErrUnsortedSet means hash values are not written in ascending order
+var ErrUnsortedSet = fmt.Errorf("strobemers: hash values of set not in ascending order")
+
+// ErrUnknownSampling means the sampling method is not supported
+var ErrUnknownSampling = fmt.Errorf("strobemers: unknown sampling method")
+
+// ErrInvalidSampling means invalid sampling parameters
+var ErrInvalidSampling = fmt.Errorf("strobemers: invalid sampling parameters")
+
+// ErrEffectiveKUnsupported means the effective k can not be computed for the parameters
+var ErrEffectiveKUnsupported = fmt.Errorf("strobemers: effective k not supported for minimizer sampling")
+
 // ------------------------------------------------------------------------
 
 func computeHashes(sequence *[]byte, k int) ([]uint64, error) {
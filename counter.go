@@ -0,0 +1,237 @@
+package strobemers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+)
+
+// DefaultCounterShards is the default number of shards of a Counter.
+const DefaultCounterShards = 64
+
+// Counter counts occurrences of strobemers. It's safe for concurrent use,
+// strobemers are distributed into shards of maps with their own locks.
+type Counter struct {
+	Params Params
+
+	shards []counterShard
+	bits   uint // shard index = hash64(hash) >> (64 - bits)
+}
+
+type counterShard struct {
+	sync.Mutex
+	counts map[uint64]uint32
+}
+
+// NewCounter creates a Counter. The number of shards is rounded up to
+// a power of 2, and DefaultCounterShards is used for a non-positive value.
+func NewCounter(p *Params, shards int) (*Counter, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if shards <= 0 {
+		shards = DefaultCounterShards
+	}
+	b := uint(bits.Len(uint(shards - 1)))
+	c := &Counter{
+		Params: *p,
+		shards: make([]counterShard, 1<<b),
+		bits:   b,
+	}
+	for i := range c.shards {
+		c.shards[i].counts = make(map[uint64]uint32, 1024)
+	}
+	return c, nil
+}
+
+func (c *Counter) shard(hash uint64) int {
+	if c.bits == 0 {
+		return 0
+	}
+	return int(hash64(hash) >> (64 - c.bits))
+}
+
+// Add counts strobemers of the positive strand of seq, e.g., a contig.
+func (c *Counter) Add(seq []byte) error {
+	return c.add(seq, false)
+}
+
+// AddBothStrands counts strobemers of seq and its reverse complement, so
+// that reads from either strand contribute to the same counts.
+func (c *Counter) AddBothStrands(seq []byte) error {
+	return c.add(seq, true)
+}
+
+func (c *Counter) add(seq []byte, both bool) error {
+	// group hashes by shards to lock each shard once
+	groups := make([][]uint64, len(c.shards))
+	var i int
+	err := addStrobemers(seq, &c.Params, both, func(hash uint64) {
+		i = c.shard(hash)
+		groups[i] = append(groups[i], hash)
+	})
+	if err != nil {
+		return err
+	}
+	for i, hashes := range groups {
+		if len(hashes) == 0 {
+			continue
+		}
+		s := &c.shards[i]
+		s.Lock()
+		for _, hash := range hashes {
+			s.add(hash, 1)
+		}
+		s.Unlock()
+	}
+	return nil
+}
+
+// AddHash counts a strobemer hash value.
+func (c *Counter) AddHash(hash uint64) {
+	s := &c.shards[c.shard(hash)]
+	s.Lock()
+	s.add(hash, 1)
+	s.Unlock()
+}
+
+// add increases the count, which saturates at math.MaxUint32.
+func (s *counterShard) add(hash uint64, n uint32) {
+	v := s.counts[hash]
+	if v > math.MaxUint32-n {
+		v = math.MaxUint32
+	} else {
+		v += n
+	}
+	s.counts[hash] = v
+}
+
+// Merge adds counts of another Counter. Each shard of o is copied while
+// holding only its lock, so concurrent merges of two Counters into each
+// other do not deadlock. Merging a Counter into itself doubles the counts.
+func (c *Counter) Merge(o *Counter) error {
+	if !c.Params.Equal(&o.Params) {
+		return ErrParamsMismatch
+	}
+	var counts map[uint64]uint32
+	for i := range o.shards {
+		os := &o.shards[i]
+		os.Lock()
+		counts = make(map[uint64]uint32, len(os.counts))
+		for hash, n := range os.counts {
+			counts[hash] = n
+		}
+		os.Unlock()
+
+		for hash, n := range counts {
+			c.AddN(hash, n)
+		}
+	}
+	return nil
+}
+
+// AddN increases the count of a strobemer hash value by n.
+func (c *Counter) AddN(hash uint64, n uint32) {
+	s := &c.shards[c.shard(hash)]
+	s.Lock()
+	s.add(hash, n)
+	s.Unlock()
+}
+
+// Count returns the count of a strobemer hash value.
+func (c *Counter) Count(hash uint64) uint32 {
+	s := &c.shards[c.shard(hash)]
+	s.Lock()
+	n := s.counts[hash]
+	s.Unlock()
+	return n
+}
+
+// Len returns the number of distinct strobemers.
+func (c *Counter) Len() int {
+	var n int
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.Lock()
+		n += len(s.counts)
+		s.Unlock()
+	}
+	return n
+}
+
+// Filter removes strobemers occurring less than minCount or more than
+// maxCount times, maxCount = 0 for no upper limit. It returns the number
+// of removed strobemers.
+func (c *Counter) Filter(minCount uint32, maxCount uint32) int {
+	if maxCount == 0 {
+		maxCount = math.MaxUint32
+	}
+	var removed int
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.Lock()
+		for hash, n := range s.counts {
+			if n < minCount || n > maxCount {
+				delete(s.counts, hash)
+				removed++
+			}
+		}
+		s.Unlock()
+	}
+	return removed
+}
+
+// Range calls f for each strobemer and its count in no particular order,
+// until f returns false. The Counter should not be modified in f.
+func (c *Counter) Range(f func(hash uint64, count uint32) bool) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.Lock()
+		for hash, n := range s.counts {
+			if !f(hash, n) {
+				s.Unlock()
+				return
+			}
+		}
+		s.Unlock()
+	}
+}
+
+// HistogramBin is a bin of an abundance histogram, i.e., the number of
+// distinct strobemers occurring Count times.
+type HistogramBin struct {
+	Count      uint32
+	Strobemers int
+}
+
+// Histogram returns the abundance histogram sorted by counts, empty bins
+// are omitted.
+func (c *Counter) Histogram() []HistogramBin {
+	m := make(map[uint32]int, 256)
+	c.Range(func(_ uint64, n uint32) bool {
+		m[n]++
+		return true
+	})
+	hist := make([]HistogramBin, 0, len(m))
+	for n, v := range m {
+		hist = append(hist, HistogramBin{Count: n, Strobemers: v})
+	}
+	sort.Slice(hist, func(i, j int) bool { return hist[i].Count < hist[j].Count })
+	return hist
+}
+
+// WriteHistogram writes the abundance histogram in two tab-delimited
+// columns: count and the number of strobemers, like "jellyfish histo".
+func (c *Counter) WriteHistogram(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, b := range c.Histogram() {
+		if _, err := fmt.Fprintf(bw, "%d\t%d\n", b.Count, b.Strobemers); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,143 @@
+package strobemers
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	r := rand.New(rand.NewSource(46))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	// a 20 kb "genome", sequenced as 5 copies in pieces, and a repeat of 10 copies
+	genome := randomSeq(r, 20000)
+	repeat := randomSeq(r, 1000)
+	var seqs [][]byte
+	for i := 0; i < 5; i++ {
+		seqs = append(seqs, genome)
+	}
+	for i := 0; i < 10; i++ {
+		seqs = append(seqs, repeat)
+	}
+
+	c, err := NewCounter(p, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.shards) != 16 {
+		t.Errorf("unexpected number of shards: %d", len(c.shards))
+	}
+	var wg sync.WaitGroup
+	for _, seq := range seqs {
+		wg.Add(1)
+		go func(seq []byte) {
+			defer wg.Done()
+			if err := c.Add(seq); err != nil {
+				t.Error(err)
+			}
+		}(seq)
+	}
+	wg.Wait()
+
+	expected := make(map[uint64]uint32, 32000)
+	for _, seq := range seqs {
+		for _, sm := range collectStrobemers(t, seq, p) {
+			expected[sm.hash]++
+		}
+	}
+	if c.Len() != len(expected) {
+		t.Fatalf("unexpected number of strobemers: %d != %d", c.Len(), len(expected))
+	}
+	for h, n := range expected {
+		if c.Count(h) != n {
+			t.Fatalf("unexpected count: %d != %d", c.Count(h), n)
+		}
+	}
+
+	hist := c.Histogram()
+	if len(hist) != 2 || hist[0].Count != 5 || hist[1].Count != 10 {
+		t.Fatalf("unexpected histogram: %v", hist)
+	}
+	buf := &bytes.Buffer{}
+	if err = c.WriteHistogram(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "5\t") {
+		t.Errorf("unexpected histogram output: %s", buf.String())
+	}
+
+	// merge
+	d, _ := NewCounter(p, 0)
+	if err = d.Merge(c); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Merge(c); err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != c.Len() || d.Histogram()[0].Count != 10 {
+		t.Errorf("unexpected merged counter: %v", d.Histogram())
+	}
+
+	// self-merge
+	e, _ := NewCounter(p, 4)
+	e.Merge(c)
+	if err = e.Merge(e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Len() != c.Len() || e.Histogram()[0].Count != 10 {
+		t.Errorf("unexpected self-merged counter: %v", e.Histogram())
+	}
+
+	// concurrent merges into each other
+	a, _ := NewCounter(p, 4)
+	b, _ := NewCounter(p, 4)
+	a.Merge(c)
+	b.Merge(c)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); a.Merge(b) }()
+		go func() { defer wg.Done(); b.Merge(a) }()
+	}
+	wg.Wait()
+	if a.Len() != c.Len() || b.Len() != c.Len() {
+		t.Errorf("unexpected numbers of strobemers after concurrent merges: %d, %d", a.Len(), b.Len())
+	}
+
+	// filter
+	removed := c.Filter(1, 9)
+	if removed != hist[1].Strobemers || c.Len() != hist[0].Strobemers {
+		t.Errorf("unexpected filtering result: %d removed, %d left", removed, c.Len())
+	}
+	c.Filter(6, 0)
+	if c.Len() != 0 {
+		t.Errorf("all strobemers should be removed")
+	}
+}
+
+func TestCounterBothStrands(t *testing.T) {
+	r := rand.New(rand.NewSource(46))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	seq := randomSeq(r, 5000)
+
+	a, _ := NewCounter(p, 0)
+	if err := a.AddBothStrands(seq); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewCounter(p, 0)
+	b.Add(seq)
+	b.Add(ReverseComplement(seq, nil))
+	if a.Len() != b.Len() {
+		t.Fatalf("unexpected number of strobemers: %d != %d", a.Len(), b.Len())
+	}
+	b.Range(func(hash uint64, n uint32) bool {
+		if a.Count(hash) != n {
+			t.Errorf("unexpected count: %d != %d", a.Count(hash), n)
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,314 @@
+package strobemers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// HashSet is a set of distinct strobemer hash values.
+type HashSet map[uint64]struct{}
+
+// NewHashSet collects distinct strobemers of the positive strands of
+// sequences, e.g., all contigs of a genome. Sequences too short to
+// produce any strobemer are skipped.
+func NewHashSet(seqs [][]byte, p *Params) (HashSet, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	s := make(HashSet, 1024)
+	var iter Iterator
+	var err error
+	var hash uint64
+	var ok bool
+	for i := range seqs {
+		iter, err = newIteratorIfLongEnough(&seqs[i], p)
+		if err != nil {
+			return nil, err
+		}
+		if iter == nil {
+			continue
+		}
+		for {
+			hash, ok = iter.Next()
+			if !ok {
+				break
+			}
+			s[hash] = struct{}{}
+		}
+	}
+	return s, nil
+}
+
+// Intersection returns the number of shared hash values.
+func Intersection(a, b HashSet) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var n int
+	var ok bool
+	for h := range a {
+		if _, ok = b[h]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Jaccard returns the Jaccard index |A∩B| / |A∪B|.
+func Jaccard(a, b HashSet) float64 {
+	inter := Intersection(a, b)
+	union := len(a) + len(b) - inter
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
+
+// Containment returns the fraction of a contained in b, i.e., |A∩B| / |A|.
+func Containment(a, b HashSet) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	return float64(Intersection(a, b)) / float64(len(a))
+}
+
+// EffectiveK returns the length of a k-mer with the same probability of
+// surviving random substitutions as a strobemer, i.e., a strobemer is
+// conserved with the probability of about (1-d)^EffectiveK at a mutation rate d.
+//
+// Unlike a k-mer, a strobemer is also destroyed by mutations elsewhere in
+// the windows of the 2nd and 3rd strobes, which create new l-mers that may
+// replace the selected ones. A mutation at distance x from the selected
+// strobe in a window of W candidate l-mers changes min(l, x) of them, each
+// replacing the selected one with the probability of about 1/W. So each
+// window adds the expected number of changed l-mers divided by W, averaged
+// over positions of the selected strobe. For wMin == wMax, it equals n*l.
+//
+// The model holds for both schemes, as either one selects the strobe with
+// the minimum of a random function of candidates. With syncmer strobes,
+// a changed l-mer is a candidate with the density of syncmers, and then
+// replaces the selected one with the probability of 1/(density*W), so the
+// window term is the same. Sampling of first strobes with syncmers or
+// modulo depends only on the first strobe itself, which is already covered
+// by n*l. Minimizer sampling also depends on neighboring l-mers of the first
+// strobe, which is not modeled, and ErrEffectiveKUnsupported is returned.
+func (p *Params) EffectiveK() (float64, error) {
+	if p.Sampling == SamplingMinimizer {
+		return 0, ErrEffectiveKUnsupported
+	}
+	w := p.WMax - p.WMin + 1
+	var sum, d int
+	for s := 0; s < w; s++ { // position of the selected strobe
+		for t := 0; t < w; t++ {
+			d = t - s
+			if d < 0 {
+				d = -d
+			}
+			sum += minInt(p.L, d)
+		}
+	}
+	return float64(p.N*p.L) + float64(p.N-1)*float64(sum)/float64(w*w), nil
+}
+
+// MashDistance estimates the mutation distance from the Jaccard index of
+// two strobemer sets, as in Mash: D = -1/k ln(2j/(1+j)), where k is the
+// effective k of the strobemers. 1 is returned for j == 0.
+func MashDistance(j float64, p *Params) (float64, error) {
+	k, err := p.EffectiveK()
+	if err != nil {
+		return 0, err
+	}
+	return mashDistance(j, k), nil
+}
+
+func mashDistance(j float64, k float64) float64 {
+	if j <= 0 {
+		return 1
+	}
+	if j >= 1 {
+		return 0
+	}
+	d := -math.Log(2*j/(1+j)) / k
+	if d > 1 {
+		return 1
+	}
+	return d
+}
+
+// ContainmentANI estimates the average nucleotide identity from the
+// containment c of a sequence in another one: ANI = c^(1/k), where k is
+// the effective k of the strobemers.
+func ContainmentANI(c float64, p *Params) (float64, error) {
+	k, err := p.EffectiveK()
+	if err != nil {
+		return 0, err
+	}
+	if c <= 0 {
+		return 0, nil
+	}
+	if c >= 1 {
+		return 1, nil
+	}
+	return math.Pow(c, 1/k), nil
+}
+
+// Comparison is the result of comparing two strobemer sets.
+type Comparison struct {
+	NA, NB  int // numbers of distinct strobemers
+	NCommon int // number of shared strobemers
+
+	Jaccard      float64
+	ContainmentA float64 // fraction of A in B
+	ContainmentB float64 // fraction of B in A
+
+	Distance float64 // Mash distance
+	ANI      float64 // 1 - Distance
+}
+
+// Compare compares two strobemer sets computed with the Params p.
+func Compare(a, b HashSet, p *Params) (*Comparison, error) {
+	k, err := p.EffectiveK()
+	if err != nil {
+		return nil, err
+	}
+	return compare(a, b, k), nil
+}
+
+// compare compares two strobemer sets with the effective k.
+func compare(a, b HashSet, k float64) *Comparison {
+	c := &Comparison{
+		NA:      len(a),
+		NB:      len(b),
+		NCommon: Intersection(a, b),
+	}
+	if c.NA > 0 {
+		c.ContainmentA = float64(c.NCommon) / float64(c.NA)
+	}
+	if c.NB > 0 {
+		c.ContainmentB = float64(c.NCommon) / float64(c.NB)
+	}
+	if union := c.NA + c.NB - c.NCommon; union > 0 {
+		c.Jaccard = float64(c.NCommon) / float64(union)
+	}
+	c.Distance = mashDistance(c.Jaccard, k)
+	c.ANI = 1 - c.Distance
+	return c
+}
+
+// DistanceMatrix contains pairwise comparisons of strobemer sets.
+type DistanceMatrix struct {
+	Names []string
+	C     [][]*Comparison // symmetric, C[i][j] and C[j][i] are the same one
+}
+
+// NewDistanceMatrix compares all pairs of strobemer sets computed with the
+// Params p, using the given number of goroutines (0 for all CPUs).
+func NewDistanceMatrix(names []string, sets []HashSet, p *Params, threads int) (*DistanceMatrix, error) {
+	if len(names) != len(sets) {
+		return nil, fmt.Errorf("strobemers: %d names given for %d sets", len(names), len(sets))
+	}
+	k, err := p.EffectiveK()
+	if err != nil {
+		return nil, err
+	}
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	n := len(sets)
+	m := &DistanceMatrix{Names: names, C: make([][]*Comparison, n)}
+	for i := range m.C {
+		m.C[i] = make([]*Comparison, n)
+	}
+
+	rows := make(chan int, n)
+	for i := 0; i < n; i++ {
+		rows <- i
+	}
+	close(rows)
+
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var c *Comparison
+			for i := range rows {
+				for j := i; j < n; j++ {
+					c = compare(sets[i], sets[j], k)
+					m.C[i][j], m.C[j][i] = c, c
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return m, nil
+}
+
+// Metric is a value of Comparison to output.
+type Metric int
+
+const (
+	// MetricDistance is the Mash distance.
+	MetricDistance Metric = iota
+	// MetricANI is the ANI estimated from the Jaccard index.
+	MetricANI
+	// MetricJaccard is the Jaccard index.
+	MetricJaccard
+	// MetricContainment is the fraction of the row sequence contained in the column one.
+	MetricContainment
+)
+
+func (m *DistanceMatrix) value(i, j int, metric Metric) float64 {
+	c := m.C[i][j]
+	switch metric {
+	case MetricANI:
+		return c.ANI
+	case MetricJaccard:
+		return c.Jaccard
+	case MetricContainment:
+		if i <= j {
+			return c.ContainmentA
+		}
+		return c.ContainmentB
+	}
+	return c.Distance
+}
+
+// WriteTSV writes the square matrix of a metric with a header line.
+func (m *DistanceMatrix) WriteTSV(w io.Writer, metric Metric) error {
+	bw := bufio.NewWriter(w)
+	for _, name := range m.Names {
+		bw.WriteByte('\t')
+		bw.WriteString(name)
+	}
+	bw.WriteByte('\n')
+	for i, name := range m.Names {
+		bw.WriteString(name)
+		for j := range m.Names {
+			fmt.Fprintf(bw, "\t%.6f", m.value(i, j, metric))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WritePairsTSV writes all comparisons of pairs i <= j, one pair per line.
+func (m *DistanceMatrix) WritePairsTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "seqA\tseqB\tnA\tnB\tnCommon\tjaccard\tcontainmentA\tcontainmentB\tdistance\tani\n")
+	var c *Comparison
+	for i := range m.Names {
+		for j := i; j < len(m.Names); j++ {
+			c = m.C[i][j]
+			fmt.Fprintf(bw, "%s\t%s\t%d\t%d\t%d\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f\n",
+				m.Names[i], m.Names[j], c.NA, c.NB, c.NCommon,
+				c.Jaccard, c.ContainmentA, c.ContainmentB, c.Distance, c.ANI)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,137 @@
+package strobemers
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// mutate substitutes bases at the rate d.
+func mutate(r *rand.Rand, seq []byte, d float64) []byte {
+	s := append([]byte{}, seq...)
+	for i := range s {
+		if r.Float64() < d {
+			s[i] = bit2base[(base2bit(s[i])+1+r.Intn(3))&3]
+		}
+	}
+	return s
+}
+
+func base2bit(b byte) int {
+	switch b {
+	case 'A':
+		return 0
+	case 'C':
+		return 1
+	case 'G':
+		return 2
+	}
+	return 3
+}
+
+func TestDistance(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	seq := randomSeq(r, 100000)
+
+	syncmers := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	syncmers.Sampling, syncmers.SyncmerS = SamplingClosedSyncmer, 11
+	syncmerStrobes := NewParams(SchemeMinStrobes, _n3, _l3, _w_min, _w_max)
+	syncmerStrobes.StrobeSyncmerS = 6
+
+	for _, p := range []*Params{
+		NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max),
+		NewParams(SchemeRandStrobes, _n3, _l3, _w_min, _w_max),
+		NewParams(SchemeMinStrobes, _n2, _l2, _w_min, _w_max),
+		NewParams(SchemeMinStrobes, _n3, _l3, _w_min, _w_max),
+		syncmers,
+		syncmerStrobes,
+	} {
+		a, err := NewHashSet([][]byte{seq}, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := Compare(a, a, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Jaccard != 1 || c.Distance != 0 || c.ANI != 1 {
+			t.Errorf("%s: unexpected self comparison: %+v", p, c)
+		}
+
+		for _, d := range []float64{0.01, 0.03, 0.05} {
+			b, _ := NewHashSet([][]byte{mutate(r, seq, d)}, p)
+			c, _ = Compare(a, b, p)
+			ani, _ := ContainmentANI(c.ContainmentA, p)
+			if debug {
+				k, _ := p.EffectiveK()
+				t.Logf("%s, d=%.2f, k=%.1f: jaccard=%.4f, distance=%.4f, ani=%.4f, containment ani=%.4f",
+					p, d, k, c.Jaccard, c.Distance, c.ANI, ani)
+			}
+			if math.Abs(c.Distance-d) > d*0.2 {
+				t.Errorf("%s: estimated distance %.4f, expected %.2f", p, c.Distance, d)
+			}
+			if math.Abs(1-ani-d) > d*0.2 {
+				t.Errorf("%s: estimated ANI from containment %.4f, expected %.2f", p, ani, 1-d)
+			}
+		}
+	}
+
+	// minimizer sampling is not modeled
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	p.Sampling, p.SampleW = SamplingMinimizer, 10
+	a, _ := NewHashSet([][]byte{seq}, p)
+	if _, err := Compare(a, a, p); err != ErrEffectiveKUnsupported {
+		t.Errorf("expected ErrEffectiveKUnsupported, got: %v", err)
+	}
+	if _, err := NewDistanceMatrix([]string{"a"}, []HashSet{a}, p, 1); err != ErrEffectiveKUnsupported {
+		t.Errorf("expected ErrEffectiveKUnsupported, got: %v", err)
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	seq := randomSeq(r, 20000)
+
+	names := []string{"s0", "s1", "s2", "s3", "s4"}
+	sets := make([]HashSet, len(names))
+	for i := range sets {
+		sets[i], _ = NewHashSet([][]byte{mutate(r, seq, float64(i)*0.01)}, p)
+	}
+
+	m, err := NewDistanceMatrix(names, sets, p, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range names {
+		if m.C[i][i].Distance != 0 {
+			t.Errorf("non-zero distance to itself: %s", names[i])
+		}
+		for j := range names {
+			if m.C[i][j] != m.C[j][i] {
+				t.Errorf("asymmetric matrix")
+			}
+		}
+		if i > 1 && m.C[0][i].Distance <= m.C[0][i-1].Distance {
+			t.Errorf("distance should increase with the mutation rate")
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err = m.WriteTSV(buf, MetricANI); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(names)+1 || len(strings.Split(lines[1], "\t")) != len(names)+1 {
+		t.Errorf("unexpected matrix:\n%s", buf.String())
+	}
+	if debug {
+		t.Logf("\n%s", buf.String())
+	}
+
+	if _, err = NewDistanceMatrix(names[1:], sets, p, 1); err == nil {
+		t.Errorf("mismatched names should be rejected")
+	}
+}
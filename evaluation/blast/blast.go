@@ -0,0 +1,168 @@
+// Package blast reads BLAST tabular results (-outfmt 6 and 7) and uses
+// HSPs as the ground truth of homology, to measure precision and recall
+// of seed hits of k-mers and strobemers.
+package blast
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidLine means a line is not a valid line of BLAST tabular format.
+var ErrInvalidLine = fmt.Errorf("blast: invalid line of tabular format")
+
+// HSP is a high-scoring segment pair in BLAST tabular format, with the 12
+// standard columns: qseqid sseqid pident length mismatch gapopen qstart
+// qend sstart send evalue bitscore. Positions are 1-based and inclusive,
+// and SStart > SEnd for HSPs on the minus strand of the subject.
+type HSP struct {
+	QSeqID string
+	SSeqID string
+
+	PIdent   float64
+	Length   int
+	Mismatch int
+	GapOpen  int
+
+	QStart, QEnd int
+	SStart, SEnd int
+
+	EValue   float64
+	BitScore float64
+}
+
+// Minus tells whether the query is aligned to the minus strand of the subject.
+func (h *HSP) Minus() bool {
+	return h.SStart > h.SEnd
+}
+
+// QueryInterval returns the 0-based, half-open interval in the query.
+func (h *HSP) QueryInterval() (int, int) {
+	return h.QStart - 1, h.QEnd
+}
+
+// SubjectInterval returns the 0-based, half-open interval on the plus
+// strand of the subject.
+func (h *HSP) SubjectInterval() (int, int) {
+	if h.Minus() {
+		return h.SEnd - 1, h.SStart
+	}
+	return h.SStart - 1, h.SEnd
+}
+
+// ParseHSP parses a line of BLAST tabular format. Extra columns are ignored.
+func ParseHSP(line string) (*HSP, error) {
+	items := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+	if len(items) < 12 {
+		return nil, fmt.Errorf("%w: %d columns: %s", ErrInvalidLine, len(items), line)
+	}
+	h := &HSP{QSeqID: items[0], SSeqID: items[1]}
+
+	var err error
+	ints := []*int{&h.Length, &h.Mismatch, &h.GapOpen, &h.QStart, &h.QEnd, &h.SStart, &h.SEnd}
+	for i, p := range ints {
+		*p, err = strconv.Atoi(strings.TrimSpace(items[i+3]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLine, line)
+		}
+	}
+	floats := []struct {
+		p *float64
+		i int
+	}{{&h.PIdent, 2}, {&h.EValue, 10}, {&h.BitScore, 11}}
+	for _, f := range floats {
+		*f.p, err = strconv.ParseFloat(strings.TrimSpace(items[f.i]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLine, line)
+		}
+	}
+	if h.QStart < 1 || h.QEnd < h.QStart || h.SStart < 1 || h.SEnd < 1 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLine, line)
+	}
+	return h, nil
+}
+
+// Reader reads HSPs from BLAST tabular output, comment lines of -outfmt 7
+// and empty lines are skipped.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewReader creates a Reader.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), 1<<20)
+	return &Reader{s: s}
+}
+
+// Read returns the next HSP, or io.EOF.
+func (r *Reader) Read() (*HSP, error) {
+	var line string
+	for r.s.Scan() {
+		r.line++
+		line = r.s.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		h, err := ParseHSP(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return h, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ReadAll reads all HSPs.
+func ReadAll(r io.Reader) ([]*HSP, error) {
+	rd := NewReader(r)
+	hsps := make([]*HSP, 0, 16)
+	for {
+		h, err := rd.Read()
+		if err == io.EOF {
+			return hsps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		hsps = append(hsps, h)
+	}
+}
+
+// ReadFile reads all HSPs from a plain or gzipped (.gz) file.
+func ReadFile(file string) ([]*HSP, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	if strings.HasSuffix(strings.ToLower(file), ".gz") {
+		gz, err := gzip.NewReader(fh)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ReadAll(r)
+}
+
+// GroupByQuery groups HSPs by query IDs.
+func GroupByQuery(hsps []*HSP) map[string][]*HSP {
+	m := make(map[string][]*HSP, 8)
+	for _, h := range hsps {
+		m[h.QSeqID] = append(m[h.QSeqID], h)
+	}
+	return m
+}
@@ -0,0 +1,106 @@
+package blast
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/shenwei356/strobemers"
+	"github.com/shenwei356/strobemers/evaluation/metrics"
+)
+
+var bases = [4]byte{'A', 'C', 'G', 'T'}
+
+func randomSeq(r *rand.Rand, n int) []byte {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = bases[r.Intn(4)]
+	}
+	return s
+}
+
+func TestReader(t *testing.T) {
+	data := `# BLASTN 2.11.0+
+# Query: q0
+# Database: r0.fasta
+# Fields: query acc.ver, subject acc.ver, % identity, alignment length, mismatches, gap opens, q. start, q. end, s. start, s. end, evalue, bit score
+# 2 hits found
+q0	r0	99.333	150	1	0	1	150	396270	396419	2.00e-74	272
+q0	r0	90.000	50	5	0	1	50	1000	951	1.5e-10	60.2
+
+q1	r0	100.000	30	0	0	11	40	21	50	1e-5	50
+`
+	hsps, err := ReadAll(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hsps) != 3 {
+		t.Fatalf("expected 3 HSPs, got %d", len(hsps))
+	}
+	h := hsps[0]
+	if h.QSeqID != "q0" || h.SSeqID != "r0" || h.PIdent != 99.333 || h.Length != 150 || h.Mismatch != 1 ||
+		h.SStart != 396270 || h.BitScore != 272 || h.Minus() {
+		t.Errorf("unexpected HSP: %+v", h)
+	}
+	if s, e := hsps[1].SubjectInterval(); !hsps[1].Minus() || s != 950 || e != 1000 {
+		t.Errorf("unexpected subject interval of a minus HSP: [%d, %d)", s, e)
+	}
+	if g := GroupByQuery(hsps); len(g["q0"]) != 2 || len(g["q1"]) != 1 {
+		t.Errorf("unexpected groups")
+	}
+
+	if _, err = ReadAll(strings.NewReader("q0\tr0\t100\n")); err == nil {
+		t.Errorf("invalid line should be rejected")
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ref := randomSeq(r, 10000)
+	query := append([]byte{}, ref[1000:1300]...)
+	query[150] = bases[(strings.IndexByte("ACGT", query[150])+1)&3]
+	copy(ref[5000:5100], ref[1000:1100]) // a repeat not found by BLAST
+
+	plus := []*HSP{{QSeqID: "q", SSeqID: "r", Length: 300, QStart: 1, QEnd: 300, SStart: 1001, SEnd: 1300}}
+	rcQuery := strobemers.ReverseComplement(query, nil)
+	minus := []*HSP{{QSeqID: "q", SSeqID: "r", Length: 300, QStart: 1, QEnd: 300, SStart: 1300, SEnd: 1001}}
+
+	for _, m := range []*metrics.Method{
+		metrics.Kmers(30),
+		metrics.Strobemers(strobemers.NewParams(strobemers.SchemeRandStrobes, 2, 15, 20, 30)),
+		metrics.Strobemers(strobemers.NewParams(strobemers.SchemeMinStrobes, 3, 10, 20, 30)),
+	} {
+		e, err := NewEvaluator([]string{"r"}, [][]byte{ref}, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := e.Evaluate(query, plus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s: %+v, precision: %.3f, recall: %.3f", m, res, res.Precision(), res.Recall())
+		if res.TrueHits == 0 || res.TrueHits == res.Hits || res.Recall() < 0.5 || res.Recall() == 1 {
+			t.Errorf("%s: unexpected result: %+v", m, res)
+		}
+
+		// the same on the other strand
+		res2, err := e.Evaluate(rcQuery, minus)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res2.TrueHits == 0 || res2.Recall() < 0.5 {
+			t.Errorf("%s: unexpected result of the minus strand: %+v", m, res2)
+		}
+
+		// wrong strand
+		res3, _ := e.Evaluate(query, minus)
+		if res3.TrueHits != 0 {
+			t.Errorf("%s: hits should not be true on the wrong strand: %+v", m, res3)
+		}
+
+		if _, err = e.Evaluate(query, []*HSP{{SSeqID: "x"}}); err == nil {
+			t.Errorf("unknown subject should be rejected")
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package blast
+
+import (
+	"fmt"
+
+	"github.com/shenwei356/strobemers"
+	"github.com/shenwei356/strobemers/evaluation/metrics"
+)
+
+// DefaultSlack is the default maximum shift between the position of a
+// seed hit in the subject and the one expected from the HSP, which
+// tolerates gaps in HSPs.
+const DefaultSlack = 10
+
+// Evaluator checks whether seed hits between queries and subjects fall
+// inside BLAST HSPs.
+type Evaluator struct {
+	m   *metrics.Method
+	idx map[string]int // subject name -> index
+
+	seeds map[uint64][]subjectSeed
+
+	// Maximum shift between the position of a hit in the subject and the
+	// one expected from the HSP.
+	Slack int
+}
+
+type subjectSeed struct {
+	subject int
+	pos     [3]int
+}
+
+// NewEvaluator computes seeds of all subject sequences.
+func NewEvaluator(names []string, seqs [][]byte, m *metrics.Method) (*Evaluator, error) {
+	if len(names) != len(seqs) {
+		return nil, fmt.Errorf("blast: %d names given for %d sequences", len(names), len(seqs))
+	}
+	e := &Evaluator{
+		m:     m,
+		idx:   make(map[string]int, len(names)),
+		seeds: make(map[uint64][]subjectSeed, 1024),
+		Slack: DefaultSlack,
+	}
+	for i, seq := range seqs {
+		e.idx[names[i]] = i
+		seeds, err := m.Seeds(seq)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range seeds {
+			e.seeds[s.Hash] = append(e.seeds[s.Hash], subjectSeed{subject: i, pos: s.Pos})
+		}
+	}
+	return e, nil
+}
+
+// Result contains the numbers of seed hits of a method.
+type Result struct {
+	Method string
+
+	Seeds      int // number of query seeds of both strands
+	Candidates int // number of query seeds inside HSPs, i.e., expected to hit
+	Recovered  int // number of candidates with at least one true hit
+
+	Hits     int // number of pairs of query and subject seeds with the same hash values
+	TrueHits int // number of hits inside HSPs
+}
+
+// Precision returns the fraction of hits inside HSPs.
+func (r *Result) Precision() float64 {
+	if r.Hits == 0 {
+		return 0
+	}
+	return float64(r.TrueHits) / float64(r.Hits)
+}
+
+// Recall returns the fraction of query seeds inside HSPs that hit the
+// homologous positions.
+func (r *Result) Recall() float64 {
+	if r.Candidates == 0 {
+		return 0
+	}
+	return float64(r.Recovered) / float64(r.Candidates)
+}
+
+// Add adds the numbers of another result, e.g., of another query.
+func (r *Result) Add(o *Result) {
+	r.Seeds += o.Seeds
+	r.Candidates += o.Candidates
+	r.Recovered += o.Recovered
+	r.Hits += o.Hits
+	r.TrueHits += o.TrueHits
+}
+
+// Evaluate evaluates seed hits of a query with its HSPs. Seeds of both
+// strands of the query are used, those of the reverse complementary
+// strand are checked against HSPs on the minus strand. K-mers are
+// canonical, so only seeds of the plus strand are used and checked
+// against HSPs of both strands.
+func (e *Evaluator) Evaluate(query []byte, hsps []*HSP) (*Result, error) {
+	res := &Result{Method: e.m.String()}
+
+	subjects := make([]int, len(hsps))
+	for i, h := range hsps {
+		s, ok := e.idx[h.SSeqID]
+		if !ok {
+			return nil, fmt.Errorf("blast: subject not found: %s", h.SSeqID)
+		}
+		subjects[i] = s
+	}
+
+	l := e.m.StrobeLen()
+	n := e.m.Order()
+	qlen := len(query)
+	canonical := e.m.Params == nil
+	strands := []bool{false, true}
+	if canonical {
+		strands = strands[:1]
+	}
+	for _, minus := range strands {
+		seq := query
+		if minus {
+			seq = strobemers.ReverseComplement(query, nil)
+		}
+		seeds, err := e.m.Seeds(seq)
+		if err != nil {
+			return nil, err
+		}
+		res.Seeds += len(seeds)
+
+		var pos [3]int
+		var candidate, recovered bool
+		for _, s := range seeds {
+			pos = s.Pos
+			if minus { // positions of strobes on the plus strand of the query
+				for i := 0; i < n; i++ {
+					pos[i] = qlen - s.Pos[i] - l
+				}
+			}
+
+			candidate = false
+			for _, h := range hsps {
+				if (canonical || h.Minus() == minus) && e.insideQuery(h, &pos) {
+					candidate = true
+					break
+				}
+			}
+
+			recovered = false
+			for _, hit := range e.seeds[s.Hash] {
+				res.Hits++
+				for i, h := range hsps {
+					if subjects[i] == hit.subject && (canonical || h.Minus() == minus) &&
+						e.insideHSP(h, &pos, &hit.pos) {
+						res.TrueHits++
+						recovered = true
+						break
+					}
+				}
+			}
+
+			if candidate {
+				res.Candidates++
+				if recovered {
+					res.Recovered++
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// insideQuery tells whether all strobes are inside the query interval of the HSP.
+func (e *Evaluator) insideQuery(h *HSP, qpos *[3]int) bool {
+	qs, qe := h.QueryInterval()
+	l := e.m.StrobeLen()
+	for i := 0; i < e.m.Order(); i++ {
+		if qpos[i] < qs || qpos[i]+l > qe {
+			return false
+		}
+	}
+	return true
+}
+
+// insideHSP tells whether all strobes of a hit are inside the HSP and close
+// to the diagonal. qpos are on the plus strand of the query, and spos are on
+// the plus strand of the subject.
+func (e *Evaluator) insideHSP(h *HSP, qpos, spos *[3]int) bool {
+	if !e.insideQuery(h, qpos) {
+		return false
+	}
+	qs, _ := h.QueryInterval()
+	ss, se := h.SubjectInterval()
+	l := e.m.StrobeLen()
+	var expected, d int
+	for i := 0; i < e.m.Order(); i++ {
+		if spos[i] < ss || spos[i]+l > se {
+			return false
+		}
+		if h.Minus() {
+			expected = h.SStart - l - (qpos[i] - qs)
+		} else {
+			expected = ss + qpos[i] - qs
+		}
+		d = spos[i] - expected
+		if d < -e.Slack || d > e.Slack {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,505 @@
+// Command evaluation compares k-mers, minstrobes and randstrobes on a grid
+// of parameters, by counting shared seeds between queries and references,
+// and optionally computing position-aware metrics from simulated reads or
+// precision and recall against BLAST HSPs.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shenwei356/bio/seqio/fastx"
+	"github.com/shenwei356/strobemers"
+	"github.com/shenwei356/strobemers/evaluation/blast"
+	"github.com/shenwei356/strobemers/evaluation/metrics"
+	"github.com/shenwei356/strobemers/evaluation/simulate"
+)
+
+var (
+	queryFile = flag.String("q", "", "query file in FASTA/FASTQ format, plain or gzipped")
+	refFile   = flag.String("r", "", "reference file in FASTA/FASTQ format, plain or gzipped")
+
+	grid     = flag.String("grid", "2,10,12,12;3,7,9,9;2,10,12,16;3,7,9,13", "parameter grid of n,l,wMin,wMax[,key=value...] with optional keys sampling, sample-w, syncmer-s, syncmer-t and strobe-syncmer-s, separated by semicolons")
+	gridFile = flag.String("grid-file", "", "file of the parameter grid, one row of -grid per line (comma or tab separated), overriding -grid")
+	methods  = flag.String("methods", "kmer,minstrobes,randstrobes", "comma separated methods: kmer (k = n*l), minstrobes, randstrobes")
+	shrink   = flag.String("shrink", "both", "shrinking the last window of strobemers: yes, no or both")
+
+	truth     = flag.Bool("truth", false, "query headers contain true origins from the simulator, compute position-aware metrics")
+	blastFile = flag.String("blast", "", "BLAST tabular result (-outfmt 6/7) of queries against references, compute precision and recall")
+
+	workers = flag.Int("j", runtime.NumCPU(), "number of workers")
+	format  = flag.String("format", "tsv", "output format: tsv or json")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [options] -q query.fasta -r ref.fasta\n\noptions:\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *queryFile == "" || *refFile == "" {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		*queryFile, *refFile = flag.Arg(0), flag.Arg(1)
+	}
+	if *format != "tsv" && *format != "json" {
+		checkError(fmt.Errorf("invalid output format: %s", *format))
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	lines := strings.Split(*grid, ";")
+	if *gridFile != "" {
+		data, err := os.ReadFile(*gridFile)
+		checkError(err)
+		lines = strings.Split(string(data), "\n")
+	}
+	paramsGrid, err := parseGrid(lines)
+	checkError(err)
+	seeders, err := seedingMethods(paramsGrid, strings.Split(*methods, ","), *shrink)
+	checkError(err)
+
+	queries, err := readSeqs(*queryFile)
+	checkError(err)
+	refs, err := readSeqs(*refFile)
+	checkError(err)
+
+	var hsps map[string][]*blast.HSP
+	if *blastFile != "" {
+		list, err := blast.ReadFile(*blastFile)
+		checkError(err)
+		hsps = blast.GroupByQuery(list)
+	}
+
+	q, _ := filepathTrimExtension(filepath.Base(*queryFile))
+	r, _ := filepathTrimExtension(filepath.Base(*refFile))
+
+	// jobs are run in parallel, and results are outputted in the order of jobs
+	results := make([]*Result, len(seeders))
+	errs := make([]error, len(seeders))
+	jobs := make(chan int, len(seeders))
+	for i := range seeders {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = evaluate(seeders[i], queries, refs, hsps)
+				if results[i] != nil {
+					results[i].Query, results[i].Ref = q, r
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err = range errs {
+		checkError(err)
+	}
+
+	outfh := bufio.NewWriter(os.Stdout)
+	if *format == "json" {
+		enc := json.NewEncoder(outfh)
+		enc.SetIndent("", "  ")
+		checkError(enc.Encode(results))
+	} else {
+		checkError(writeTSV(outfh, results, *truth, hsps != nil))
+	}
+	checkError(outfh.Flush())
+}
+
+// Result contains all counts and metrics of a seeding method.
+type Result struct {
+	Query  string `json:"query"`
+	Ref    string `json:"ref"`
+	Method string `json:"method"`
+
+	NQuery  int     `json:"nQuery"`  // distinct seeds in queries
+	NRef    int     `json:"nRef"`    // distinct seeds in references
+	NCommon int     `json:"nCommon"` // shared distinct seeds
+	QCov    float64 `json:"qCov"`    // nCommon / nQuery * 100
+
+	Truth *TruthMetrics `json:"truth,omitempty"`
+	Blast *BlastMetrics `json:"blast,omitempty"`
+}
+
+// TruthMetrics contains position-aware metrics of all queries, computed
+// with their true origins.
+type TruthMetrics struct {
+	Seeds    int     `json:"seeds"`
+	Hits     int     `json:"hits"`
+	TrueHits int     `json:"trueHits"`
+	SeqCov   float64 `json:"seqCov"`
+	MatchCov float64 `json:"matchCov"`
+	Islands  int     `json:"islands"`
+	ESize    float64 `json:"eSize"`
+	EHits    float64 `json:"eHits"`
+}
+
+// BlastMetrics contains precision and recall of seed hits against BLAST HSPs.
+type BlastMetrics struct {
+	Seeds      int     `json:"seeds"`
+	Candidates int     `json:"candidates"`
+	Recovered  int     `json:"recovered"`
+	Hits       int     `json:"hits"`
+	TrueHits   int     `json:"trueHits"`
+	Precision  float64 `json:"precision"`
+	Recall     float64 `json:"recall"`
+}
+
+type record struct {
+	id, name string
+	seq      []byte
+}
+
+func evaluate(m *metrics.Method, queries, refs []record, hsps map[string][]*blast.HSP) (*Result, error) {
+	res := &Result{Method: m.String()}
+
+	setQ, err := hashSet(m, queries)
+	if err != nil {
+		return nil, err
+	}
+	setR, err := hashSet(m, refs)
+	if err != nil {
+		return nil, err
+	}
+	res.NQuery, res.NRef = len(setQ), len(setR)
+	res.NCommon = strobemers.Intersection(setQ, setR)
+	if res.NQuery > 0 {
+		res.QCov = float64(res.NCommon) / float64(res.NQuery) * 100
+	}
+
+	if *truth {
+		res.Truth, err = evaluateTruth(m, queries, refs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hsps != nil {
+		res.Blast, err = evaluateBlast(m, queries, refs, hsps)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func hashSet(m *metrics.Method, records []record) (strobemers.HashSet, error) {
+	set := make(strobemers.HashSet, 1024)
+	for _, r := range records {
+		seeds, err := m.Seeds(r.seq)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range seeds {
+			set[s.Hash] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+func evaluateTruth(m *metrics.Method, queries, refs []record) (*TruthMetrics, error) {
+	references := make(map[string]*metrics.Reference, len(refs))
+	t := &TruthMetrics{}
+	var nSeeds float64
+	for _, r := range refs {
+		ref, err := metrics.NewReference(r.seq, m)
+		if err != nil {
+			return nil, err
+		}
+		// reads may be simulated with either IDs or full names of references
+		references[r.id], references[r.name] = ref, ref
+		n := float64(ref.NumSeeds())
+		t.EHits += ref.EHits() * n
+		nSeeds += n
+	}
+	if nSeeds > 0 {
+		t.EHits /= nSeeds
+	}
+
+	var bases, seqCov, matchCov, eSize float64
+	for _, q := range queries {
+		read, err := simulate.ParseHeader(q.name)
+		if err != nil {
+			return nil, err
+		}
+		ref, ok := references[read.RefName]
+		if !ok {
+			return nil, fmt.Errorf("reference of %s not found: %s", read.Name, read.RefName)
+		}
+		read.Seq = q.seq
+		res, err := ref.Evaluate(read.Forward(), read.Truth())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", read.Name, err)
+		}
+
+		t.Seeds += res.Seeds
+		t.Hits += res.Hits
+		t.TrueHits += res.TrueHits
+		t.Islands += res.Islands
+		n := float64(res.QueryLen)
+		bases += n
+		seqCov += res.SeqCov * n
+		matchCov += res.MatchCov * n
+		eSize += res.ESize * n
+	}
+	if bases > 0 {
+		t.SeqCov, t.MatchCov, t.ESize = seqCov/bases, matchCov/bases, eSize/bases
+	}
+	return t, nil
+}
+
+func evaluateBlast(m *metrics.Method, queries, refs []record, hsps map[string][]*blast.HSP) (*BlastMetrics, error) {
+	names := make([]string, len(refs))
+	seqs := make([][]byte, len(refs))
+	for i, r := range refs {
+		names[i], seqs[i] = r.id, r.seq
+	}
+	e, err := blast.NewEvaluator(names, seqs, m)
+	if err != nil {
+		return nil, err
+	}
+
+	total := &blast.Result{}
+	for _, q := range queries {
+		res, err := e.Evaluate(q.seq, hsps[q.id])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", q.id, err)
+		}
+		total.Add(res)
+	}
+	return &BlastMetrics{
+		Seeds:      total.Seeds,
+		Candidates: total.Candidates,
+		Recovered:  total.Recovered,
+		Hits:       total.Hits,
+		TrueHits:   total.TrueHits,
+		Precision:  total.Precision(),
+		Recall:     total.Recall(),
+	}, nil
+}
+
+func writeTSV(w io.Writer, results []*Result, withTruth, withBlast bool) error {
+	header := "query\tref\tmethod\tnQuery\tnRef\tnCommon\tqCov"
+	if withTruth {
+		header += "\tseeds\thits\ttrueHits\tseqCov\tmatchCov\tislands\teSize\teHits"
+	}
+	if withBlast {
+		header += "\tblastSeeds\tcandidates\trecovered\tblastHits\tblastTrueHits\tprecision\trecall"
+	}
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%.2f", r.Query, r.Ref, r.Method, r.NQuery, r.NRef, r.NCommon, r.QCov)
+		if t := r.Truth; t != nil {
+			fmt.Fprintf(w, "\t%d\t%d\t%d\t%.4f\t%.4f\t%d\t%.2f\t%.4f",
+				t.Seeds, t.Hits, t.TrueHits, t.SeqCov, t.MatchCov, t.Islands, t.ESize, t.EHits)
+		}
+		if b := r.Blast; b != nil {
+			fmt.Fprintf(w, "\t%d\t%d\t%d\t%d\t%d\t%.4f\t%.4f",
+				b.Seeds, b.Candidates, b.Recovered, b.Hits, b.TrueHits, b.Precision, b.Recall)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gridRow is a row of the parameter grid.
+type gridRow struct {
+	n, l, wMin, wMax int
+
+	// optional sampling of strobes, see strobemers.Params
+	sampling       strobemers.Sampling
+	sampleW        int
+	syncmerS       int
+	syncmerT       int
+	strobeSyncmerS int
+}
+
+// parseGrid parses lines of n,l,wMin,wMax, optionally followed by
+// key=value columns of sampling, sample-w, syncmer-s, syncmer-t and
+// strobe-syncmer-s. Empty lines and lines starting with # are skipped.
+func parseGrid(lines []string) ([]gridRow, error) {
+	grid := make([]gridRow, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		items := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == '\t' || r == ' ' })
+		if len(items) < 4 {
+			return nil, fmt.Errorf("invalid parameters, n,l,wMin,wMax expected: %s", line)
+		}
+		var g gridRow
+		var err error
+		for i, v := range []*int{&g.n, &g.l, &g.wMin, &g.wMax} {
+			*v, err = strconv.Atoi(items[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameters, n,l,wMin,wMax expected: %s", line)
+			}
+		}
+		for _, item := range items[4:] {
+			i := strings.IndexByte(item, '=')
+			if i < 0 {
+				return nil, fmt.Errorf("invalid parameter, key=value expected: %s", item)
+			}
+			key, value := item[:i], item[i+1:]
+			if key == "sampling" {
+				if g.sampling, err = parseSampling(value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var v *int
+			switch key {
+			case "sample-w":
+				v = &g.sampleW
+			case "syncmer-s":
+				v = &g.syncmerS
+			case "syncmer-t":
+				v = &g.syncmerT
+			case "strobe-syncmer-s":
+				v = &g.strobeSyncmerS
+			default:
+				return nil, fmt.Errorf("unknown parameter: %s", key)
+			}
+			if *v, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid value of %s: %s", key, value)
+			}
+		}
+		grid = append(grid, g)
+	}
+	if len(grid) == 0 {
+		return nil, fmt.Errorf("empty parameter grid")
+	}
+	return grid, nil
+}
+
+func parseSampling(s string) (strobemers.Sampling, error) {
+	for _, v := range []strobemers.Sampling{strobemers.SamplingNone, strobemers.SamplingMinimizer,
+		strobemers.SamplingOpenSyncmer, strobemers.SamplingClosedSyncmer, strobemers.SamplingModulo} {
+		if strings.EqualFold(s, v.String()) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid sampling method: %s", s)
+}
+
+// seedingMethods returns distinct methods of all parameters, in the order
+// of the grid. Rows sharing n*l share the k-mer baseline, which is only
+// evaluated once.
+func seedingMethods(grid []gridRow, names []string, shrink string) ([]*metrics.Method, error) {
+	var shrinks []bool
+	switch shrink {
+	case "yes":
+		shrinks = []bool{true}
+	case "no":
+		shrinks = []bool{false}
+	case "both":
+		shrinks = []bool{true, false}
+	default:
+		return nil, fmt.Errorf("invalid value of -shrink: %s", shrink)
+	}
+
+	list := make([]*metrics.Method, 0, len(grid)*len(names)*2)
+	seen := make(map[string]struct{}, cap(list))
+	add := func(m *metrics.Method) {
+		if _, ok := seen[m.String()]; !ok {
+			seen[m.String()] = struct{}{}
+			list = append(list, m)
+		}
+	}
+	for _, g := range grid {
+		for _, name := range names {
+			var scheme strobemers.Scheme
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "kmer", "kmers":
+				add(metrics.Kmers(g.n * g.l))
+				continue
+			case "minstrobes":
+				scheme = strobemers.SchemeMinStrobes
+			case "randstrobes":
+				scheme = strobemers.SchemeRandStrobes
+			default:
+				return nil, fmt.Errorf("unknown method: %s", name)
+			}
+			for _, s := range shrinks {
+				p := strobemers.NewParams(scheme, g.n, g.l, g.wMin, g.wMax)
+				p.Shrink = s
+				p.Sampling, p.SampleW, p.SyncmerS, p.SyncmerT = g.sampling, g.sampleW, g.syncmerS, g.syncmerT
+				p.StrobeSyncmerS = g.strobeSyncmerS
+				if err := p.Validate(); err != nil {
+					return nil, fmt.Errorf("%s: %s", p, err)
+				}
+				add(metrics.Strobemers(p))
+			}
+		}
+	}
+	return list, nil
+}
+
+func checkError(e error) {
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", e)
+		os.Exit(1)
+	}
+}
+
+func readSeqs(file string) ([]record, error) {
+	reader, err := fastx.NewDefaultReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	records := make([]record, 0, 8)
+	var r *fastx.Record
+	for {
+		r, err = reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		records = append(records, record{
+			id:   string(r.ID),
+			name: string(r.Name),
+			seq:  append([]byte{}, r.Seq.Seq...),
+		})
+	}
+	return records, nil
+}
+
+func filepathTrimExtension(file string) (string, string) {
+	gz := strings.HasSuffix(file, ".gz") || strings.HasSuffix(file, ".GZ")
+	if gz {
+		file = file[0 : len(file)-3]
+	}
+	extension := filepath.Ext(file)
+	name := file[0 : len(file)-len(extension)]
+	if gz {
+		extension += ".gz"
+	}
+	return name, extension
+}
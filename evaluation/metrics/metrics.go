@@ -0,0 +1,350 @@
+// Package metrics evaluates seeding methods (k-mers, minstrobes and
+// randstrobes) with position-aware metrics used in the strobemers paper
+// (Sahlin 2021, Genome Research): sequence coverage, match coverage,
+// number of islands and expected island size, computed only from true
+// matches between a query and its known origin in the reference.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shenwei356/strobemers"
+	"github.com/will-rowe/nthash"
+)
+
+// ErrTruthLength means the length of the truth does not match the query.
+var ErrTruthLength = fmt.Errorf("metrics: length of truth positions does not match the query")
+
+// Method is a seeding method, k-mers or strobemers.
+type Method struct {
+	K      int                // k-mer size, for k-mers
+	Params *strobemers.Params // parameters of strobemers, nil for k-mers
+}
+
+// Kmers returns a Method of k-mers with canonical ntHash values.
+func Kmers(k int) *Method {
+	return &Method{K: k}
+}
+
+// Strobemers returns a Method of strobemers.
+func Strobemers(p *strobemers.Params) *Method {
+	return &Method{Params: p}
+}
+
+// String returns the description of the method, e.g., Kmer(30),
+// RandStrobes(2,15,20,30) and RandStrobes(2,15,20,30,shrink)+closed-syncmer(s=11),
+// i.e., Params.String() with the shrink flag.
+func (m *Method) String() string {
+	if m.Params == nil {
+		return fmt.Sprintf("Kmer(%d)", m.K)
+	}
+	s := m.Params.String()
+	if m.Params.Shrink {
+		i := strings.IndexByte(s, ')')
+		s = s[:i] + ",shrink" + s[i:]
+	}
+	return s
+}
+
+// Order returns the number of strobes, 1 for k-mers.
+func (m *Method) Order() int {
+	if m.Params == nil {
+		return 1
+	}
+	return m.Params.N
+}
+
+// StrobeLen returns the length of strobes, or k for k-mers.
+func (m *Method) StrobeLen() int {
+	if m.Params == nil {
+		return m.K
+	}
+	return m.Params.L
+}
+
+// Seed is a k-mer or strobemer with start positions of its strobes,
+// only the first Order() positions are used.
+type Seed struct {
+	Hash uint64
+	Pos  [3]int
+}
+
+// Seeds returns all seeds of a sequence. Sequences too short to produce
+// any seed are not treated as errors.
+func (m *Method) Seeds(seq []byte) ([]Seed, error) {
+	if m.Params == nil {
+		return kmerSeeds(seq, m.K)
+	}
+	return strobemerSeeds(seq, m.Params)
+}
+
+func kmerSeeds(seq []byte, k int) ([]Seed, error) {
+	if k < 1 {
+		return nil, strobemers.ErrStrobeLengthTooSmall
+	}
+	if len(seq) < k {
+		return nil, nil
+	}
+	hasher, err := nthash.NewHasher(&seq, uint(k))
+	if err != nil {
+		return nil, err
+	}
+	seeds := make([]Seed, 0, len(seq)-k+1)
+	var hash uint64
+	var ok bool
+	for i := 0; ; i++ {
+		hash, ok = hasher.Next(true)
+		if !ok {
+			break
+		}
+		seeds = append(seeds, Seed{Hash: hash, Pos: [3]int{i}})
+	}
+	return seeds, nil
+}
+
+func strobemerSeeds(seq []byte, p *strobemers.Params) ([]Seed, error) {
+	if len(seq) < p.N*p.L {
+		return nil, nil
+	}
+	iter, err := strobemers.NewIterator(&seq, p)
+	if err != nil {
+		if err == strobemers.ErrSequenceTooShort {
+			return nil, nil
+		}
+		return nil, err
+	}
+	seeds := make([]Seed, 0, len(seq))
+	var hash uint64
+	var ok bool
+	var s Seed
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+		s = Seed{Hash: hash}
+		copy(s.Pos[:p.N], iter.Indexes())
+		seeds = append(seeds, s)
+	}
+	return seeds, nil
+}
+
+// Reference contains all seeds of a reference sequence.
+type Reference struct {
+	m   *Method
+	seq []byte
+
+	seeds map[uint64][][3]int // hash -> positions of strobes
+}
+
+// NewReference computes seeds of a reference sequence.
+func NewReference(seq []byte, m *Method) (*Reference, error) {
+	seeds, err := m.Seeds(seq)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reference{m: m, seq: seq, seeds: make(map[uint64][][3]int, len(seeds))}
+	for _, s := range seeds {
+		r.seeds[s.Hash] = append(r.seeds[s.Hash], s.Pos)
+	}
+	return r, nil
+}
+
+// Method returns the seeding method.
+func (r *Reference) Method() *Method {
+	return r.m
+}
+
+// NumSeeds returns the number of distinct seeds.
+func (r *Reference) NumSeeds() int {
+	return len(r.seeds)
+}
+
+// EHits returns the expected number of hits of a seed sampled from the
+// reference, i.e., sum(c_i^2) / sum(c_i), where c_i is the number of
+// occurrences of the i-th distinct seed. It's 1 for a repeat-free reference.
+func (r *Reference) EHits() float64 {
+	var sum, sum2 float64
+	var c float64
+	for _, pos := range r.seeds {
+		c = float64(len(pos))
+		sum += c
+		sum2 += c * c
+	}
+	if sum == 0 {
+		return 0
+	}
+	return sum2 / sum
+}
+
+// Result contains metrics of seeds of a query.
+type Result struct {
+	QueryLen int
+
+	Seeds    int // number of seeds in the query
+	Hits     int // number of query seeds sharing hash values with the reference
+	TrueHits int // number of query seeds matching the reference at the true positions
+
+	SeqCov   float64 // fraction of query bases covered by strobes of true hits
+	MatchCov float64 // fraction of query bases covered by true hits, from the first to the last strobe
+	Islands  int     // number of maximal regions not covered by true hits
+	ESize    float64 // expected size of the island covering a random query base
+}
+
+// FalseHits returns the number of hits only at wrong positions, caused by
+// hash collisions or repeats.
+func (res *Result) FalseHits() int {
+	return res.Hits - res.TrueHits
+}
+
+// Precision returns the fraction of hits that are true.
+func (res *Result) Precision() float64 {
+	if res.Hits == 0 {
+		return 0
+	}
+	return float64(res.TrueHits) / float64(res.Hits)
+}
+
+// Collinear returns the truth positions of a query identical to the
+// reference region starting at start.
+func Collinear(start int, n int) []int {
+	truth := make([]int, n)
+	for i := range truth {
+		truth[i] = start + i
+	}
+	return truth
+}
+
+// Evaluate evaluates the seeds of a query against the reference.
+// truth contains the position in the reference of every query base,
+// and -1 for inserted bases. The query should be on the same strand
+// as its origin.
+//
+// A hit is true if every strobe of the query seed is mapped to the
+// corresponding strobe of a reference seed with the same hash value by
+// truth, and their sequences are identical, so hash collisions and hits
+// in repeats are not counted.
+func (r *Reference) Evaluate(query []byte, truth []int) (*Result, error) {
+	if len(truth) != len(query) {
+		return nil, ErrTruthLength
+	}
+	seeds, err := r.m.Seeds(query)
+	if err != nil {
+		return nil, err
+	}
+
+	n, l := r.m.Order(), r.m.StrobeLen()
+	res := &Result{QueryLen: len(query), Seeds: len(seeds)}
+
+	strobes := make([]interval, 0, len(seeds)*n)
+	matches := make([]interval, 0, len(seeds))
+	var i int
+	var ok, found bool
+	var hits [][3]int
+	for _, s := range seeds {
+		hits, ok = r.seeds[s.Hash]
+		if !ok {
+			continue
+		}
+		res.Hits++
+
+		found = false
+		for _, pos := range hits {
+			if r.isTrueHit(query, truth, &s.Pos, &pos) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		res.TrueHits++
+
+		start, end := s.Pos[0], s.Pos[0]+l
+		for i = 0; i < n; i++ {
+			strobes = append(strobes, interval{s.Pos[i], s.Pos[i] + l})
+			if s.Pos[i] < start {
+				start = s.Pos[i]
+			}
+			if s.Pos[i]+l > end {
+				end = s.Pos[i] + l
+			}
+		}
+		matches = append(matches, interval{start, end})
+	}
+
+	if len(query) == 0 {
+		return res, nil
+	}
+	res.SeqCov = float64(coveredBases(merge(strobes))) / float64(len(query))
+
+	matches = merge(matches)
+	res.MatchCov = float64(coveredBases(matches)) / float64(len(query))
+	var sum2 float64
+	var gap int
+	prev := 0
+	for _, m := range append(matches, interval{len(query), len(query)}) {
+		gap = m.s - prev
+		if gap > 0 {
+			res.Islands++
+			sum2 += float64(gap) * float64(gap)
+		}
+		prev = m.e
+	}
+	res.ESize = sum2 / float64(len(query))
+	return res, nil
+}
+
+func (r *Reference) isTrueHit(query []byte, truth []int, qpos, rpos *[3]int) bool {
+	n, l := r.m.Order(), r.m.StrobeLen()
+	var q, p, k int
+	for i := 0; i < n; i++ {
+		q, p = qpos[i], rpos[i]
+		for k = 0; k < l; k++ {
+			if truth[q+k] != p+k {
+				return false
+			}
+		}
+		if !bytes.Equal(query[q:q+l], r.seq[p:p+l]) {
+			return false
+		}
+	}
+	return true
+}
+
+// interval is a half-open interval.
+type interval struct {
+	s, e int
+}
+
+// merge sorts and merges overlapping intervals in place.
+func merge(list []interval) []interval {
+	if len(list) == 0 {
+		return list
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].s < list[j].s })
+	merged := list[:1]
+	var last *interval
+	for _, v := range list[1:] {
+		last = &merged[len(merged)-1]
+		if v.s <= last.e {
+			if v.e > last.e {
+				last.e = v.e
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+func coveredBases(merged []interval) int {
+	var n int
+	for _, v := range merged {
+		n += v.e - v.s
+	}
+	return n
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shenwei356/strobemers"
+)
+
+var bit2base = [4]byte{'A', 'C', 'G', 'T'}
+
+func randomSeq(r *rand.Rand, n int) []byte {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = bit2base[r.Intn(4)]
+	}
+	return s
+}
+
+func TestKmers(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ref := randomSeq(r, 10000)
+	m := Kmers(30)
+	rf, err := NewReference(ref, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := rf.EHits(); math.Abs(e-1) > 1e-9 {
+		t.Errorf("unexpected E-hits of a random sequence: %f", e)
+	}
+
+	// identical
+	query := append([]byte{}, ref[2000:2150]...)
+	res, err := rf.Evaluate(query, Collinear(2000, len(query)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Seeds != 121 || res.TrueHits != 121 || res.SeqCov != 1 || res.MatchCov != 1 || res.Islands != 0 {
+		t.Errorf("unexpected result of an identical query: %+v", res)
+	}
+
+	// a SNP
+	query[75] = bit2base[(indexOf(query[75])+1)&3]
+	res, _ = rf.Evaluate(query, Collinear(2000, len(query)))
+	if res.TrueHits != 121-30 || res.Islands != 1 || res.ESize != 1.0/150 || res.SeqCov != 149.0/150 {
+		t.Errorf("unexpected result of a query with a SNP: %+v", res)
+	}
+
+	// wrong truth: all hits are false
+	res, _ = rf.Evaluate(query, Collinear(3000, len(query)))
+	if res.Hits != 121-30 || res.TrueHits != 0 || res.FalseHits() != res.Hits || res.MatchCov != 0 || res.Islands != 1 {
+		t.Errorf("unexpected result of a wrong truth: %+v", res)
+	}
+
+	// an insertion
+	query = append(append(append([]byte{}, ref[2000:2075]...), 'A'), ref[2075:2150]...)
+	truth := append(append(Collinear(2000, 75), -1), Collinear(2075, 75)...)
+	res, _ = rf.Evaluate(query, truth)
+	if res.TrueHits != 122-30 || res.Islands != 1 || res.MatchCov != 150.0/151 {
+		t.Errorf("unexpected result of a query with an insertion: %+v", res)
+	}
+
+	if _, err = rf.Evaluate(query, truth[1:]); err != ErrTruthLength {
+		t.Errorf("truth of wrong length should be rejected")
+	}
+}
+
+func indexOf(b byte) int {
+	for i, c := range bit2base {
+		if c == b {
+			return i
+		}
+	}
+	return 0
+}
+
+func TestStrobemers(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	ref := randomSeq(r, 10000)
+	copy(ref[6000:6500], ref[1000:1500]) // a repeat
+
+	query := append([]byte{}, ref[1100:1400]...)
+	for _, i := range []int{50, 120, 200} {
+		query[i] = bit2base[(indexOf(query[i])+1)&3]
+	}
+	truth := Collinear(1100, len(query))
+
+	for _, p := range []*strobemers.Params{
+		strobemers.NewParams(strobemers.SchemeMinStrobes, 2, 15, 20, 30),
+		strobemers.NewParams(strobemers.SchemeRandStrobes, 2, 15, 20, 30),
+		strobemers.NewParams(strobemers.SchemeRandStrobes, 3, 10, 20, 30),
+	} {
+		m := Strobemers(p)
+		rf, err := NewReference(ref, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rf.EHits() <= 1 {
+			t.Errorf("%s: E-hits should be > 1 for a reference with repeats", m)
+		}
+
+		res, err := rf.Evaluate(query, truth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s: %+v", m, res)
+		if res.TrueHits == 0 || res.TrueHits > res.Hits || res.Hits > res.Seeds {
+			t.Errorf("%s: unexpected numbers of hits: %+v", m, res)
+		}
+		if res.SeqCov > res.MatchCov || res.MatchCov > 1 || res.MatchCov < 0.5 {
+			t.Errorf("%s: unexpected coverage: %+v", m, res)
+		}
+
+		// the repeat copy is not the origin, but the other copy contains the true hits
+		other, _ := rf.Evaluate(query, Collinear(6100, len(query)))
+		if other.TrueHits != res.TrueHits {
+			t.Errorf("%s: hits at the repeat copy should be true", m)
+		}
+	}
+}
+
+func TestMethodString(t *testing.T) {
+	p := strobemers.NewParams(strobemers.SchemeRandStrobes, 2, 15, 20, 30)
+	p.Shrink = false
+	sampled := *p
+	sampled.Sampling, sampled.SyncmerS = strobemers.SamplingClosedSyncmer, 11
+	shrinked := sampled
+	shrinked.Shrink = true
+
+	for _, c := range []struct {
+		m     *Method
+		label string
+	}{
+		{Kmers(30), "Kmer(30)"},
+		{Strobemers(p), "RandStrobes(2,15,20,30)"},
+		{Strobemers(&sampled), "RandStrobes(2,15,20,30)+closed-syncmer(s=11)"},
+		{Strobemers(&shrinked), "RandStrobes(2,15,20,30,shrink)+closed-syncmer(s=11)"},
+	} {
+		if s := c.m.String(); s != c.label {
+			t.Errorf("unexpected label: %s, expected %s", s, c.label)
+		}
+	}
+}
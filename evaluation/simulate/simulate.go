@@ -0,0 +1,355 @@
+// Package simulate samples fragments from reference sequences and applies
+// random substitutions, insertions and deletions, recording the true origin
+// and mutations of every read, for evaluating seeding methods across
+// error rates.
+package simulate
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shenwei356/strobemers"
+)
+
+var (
+	// ErrNoReferences means no reference sequence is long enough.
+	ErrNoReferences = fmt.Errorf("simulate: no reference sequences long enough")
+	// ErrInvalidRates means the mutation rates are invalid.
+	ErrInvalidRates = fmt.Errorf("simulate: mutation rates should be in [0, 1) and sum to < 1")
+	// ErrInvalidHeader means a header can not be parsed.
+	ErrInvalidHeader = fmt.Errorf("simulate: invalid read header")
+)
+
+// Options contains the parameters of simulation.
+type Options struct {
+	Length int // fragment length in the reference
+
+	SubRate float64 // substitution rate per base
+	InsRate float64 // insertion rate per base, a base is inserted before the reference base
+	DelRate float64 // deletion rate per base
+
+	RevComp bool // reverse complement reads with the probability of 0.5
+
+	Seed   int64  // seed of the random number generator
+	Prefix string // prefix of read names
+}
+
+// DefaultOptions is the default Options.
+var DefaultOptions = Options{
+	Length:  150,
+	SubRate: 0.01,
+	InsRate: 0.001,
+	DelRate: 0.001,
+	RevComp: true,
+	Seed:    1,
+	Prefix:  "read_",
+}
+
+// Mutation types
+const (
+	Substitution byte = 'X'
+	Insertion    byte = 'I'
+	Deletion     byte = 'D'
+)
+
+// Mutation is a single-base mutation on the positive strand of the reference.
+type Mutation struct {
+	Type byte
+	Pos  int  // 0-based position in the reference, insertions are before this base
+	Ref  byte // reference base, 0 for insertions
+	Alt  byte // new base, 0 for deletions
+}
+
+// String returns the mutation in the format of pos:type:ref:alt,
+// with - for missing bases, e.g., 105:X:A:C, 110:I:-:G and 120:D:T:-.
+func (m Mutation) String() string {
+	ref, alt := "-", "-"
+	if m.Ref != 0 {
+		ref = string(m.Ref)
+	}
+	if m.Alt != 0 {
+		alt = string(m.Alt)
+	}
+	return fmt.Sprintf("%d:%c:%s:%s", m.Pos, m.Type, ref, alt)
+}
+
+// ParseMutation parses a mutation from the format of Mutation.String.
+func ParseMutation(s string) (Mutation, error) {
+	var m Mutation
+	items := strings.Split(s, ":")
+	if len(items) != 4 || len(items[1]) != 1 || len(items[2]) != 1 || len(items[3]) != 1 {
+		return m, fmt.Errorf("%w: mutation %s", ErrInvalidHeader, s)
+	}
+	var err error
+	m.Pos, err = strconv.Atoi(items[0])
+	if err != nil {
+		return m, fmt.Errorf("%w: mutation %s", ErrInvalidHeader, s)
+	}
+	m.Type = items[1][0]
+	if m.Type != Substitution && m.Type != Insertion && m.Type != Deletion {
+		return m, fmt.Errorf("%w: mutation %s", ErrInvalidHeader, s)
+	}
+	if items[2] != "-" {
+		m.Ref = items[2][0]
+	}
+	if items[3] != "-" {
+		m.Alt = items[3][0]
+	}
+	return m, nil
+}
+
+// Read is a simulated read with its true origin.
+type Read struct {
+	Name string
+	Seq  []byte // on the strand of Rev
+
+	RefName          string
+	RefStart, RefEnd int  // 0-based, half-open interval of the origin
+	Rev              bool // whether the read is reverse complemented
+
+	Mutations []Mutation // sorted by positions, insertions come before other mutations at the same position
+}
+
+// Forward returns the sequence on the positive strand of the reference.
+func (r *Read) Forward() []byte {
+	if !r.Rev {
+		return r.Seq
+	}
+	return strobemers.ReverseComplement(r.Seq, nil)
+}
+
+// Truth returns the position in the reference of every base of Forward(),
+// and -1 for inserted bases.
+func (r *Read) Truth() []int {
+	truth := make([]int, 0, len(r.Seq))
+	k := 0
+	var m *Mutation
+	for p := r.RefStart; p < r.RefEnd; p++ {
+		deleted := false
+		for ; k < len(r.Mutations) && r.Mutations[k].Pos == p; k++ {
+			m = &r.Mutations[k]
+			switch m.Type {
+			case Insertion:
+				truth = append(truth, -1)
+			case Deletion:
+				deleted = true
+			}
+		}
+		if !deleted {
+			truth = append(truth, p)
+		}
+	}
+	return truth
+}
+
+// Counts returns the numbers of substitutions, insertions and deletions.
+func (r *Read) Counts() (sub, ins, del int) {
+	for _, m := range r.Mutations {
+		switch m.Type {
+		case Substitution:
+			sub++
+		case Insertion:
+			ins++
+		case Deletion:
+			del++
+		}
+	}
+	return
+}
+
+// Header returns the FASTA/FASTQ header of the read, containing the
+// origin and mutations, e.g.,
+//
+//	read_1 ref=chr1 start=100 end=250 strand=- muts=105:X:A:C,110:I:-:G
+//
+// muts is - if there's no mutation. The read name should not contain
+// spaces, while the reference name may.
+func (r *Read) Header() string {
+	strand := "+"
+	if r.Rev {
+		strand = "-"
+	}
+	return fmt.Sprintf("%s ref=%s start=%d end=%d strand=%s muts=%s",
+		r.Name, r.RefName, r.RefStart, r.RefEnd, strand, r.mutationsString())
+}
+
+func (r *Read) mutationsString() string {
+	if len(r.Mutations) == 0 {
+		return "-"
+	}
+	muts := make([]string, len(r.Mutations))
+	for i, m := range r.Mutations {
+		muts[i] = m.String()
+	}
+	return strings.Join(muts, ",")
+}
+
+// ParseHeader parses the origin and mutations from a header created by
+// Header. The sequence is not set. The reference name may contain spaces,
+// as the other fields are split from the right.
+func ParseHeader(header string) (*Read, error) {
+	// name ref=..., followed by 4 fields of the origin and mutations
+	i := strings.Index(header, " ref=")
+	j := strings.LastIndex(header, " start=")
+	if i < 0 || j < i+5 || strings.ContainsAny(header[:i], " \t") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+	}
+	fields := strings.Fields(header[j:])
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+	}
+	r := &Read{Name: header[:i]}
+	kv := make(map[string]string, 5)
+	kv["ref"] = header[i+5 : j]
+	for _, f := range fields {
+		i := strings.IndexByte(f, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+		}
+		kv[f[:i]] = f[i+1:]
+	}
+
+	var err, err2 error
+	r.RefName = kv["ref"]
+	r.RefStart, err = strconv.Atoi(kv["start"])
+	r.RefEnd, err2 = strconv.Atoi(kv["end"])
+	if r.RefName == "" || err != nil || err2 != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+	}
+	switch kv["strand"] {
+	case "+":
+	case "-":
+		r.Rev = true
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+	}
+
+	muts, ok := kv["muts"]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHeader, header)
+	}
+	if muts != "-" {
+		for _, s := range strings.Split(muts, ",") {
+			m, err := ParseMutation(s)
+			if err != nil {
+				return nil, err
+			}
+			r.Mutations = append(r.Mutations, m)
+		}
+	}
+	return r, nil
+}
+
+// Simulator simulates reads from reference sequences. References are
+// sampled with probabilities proportional to their lengths. Reads from
+// the same Options and references are reproducible.
+type Simulator struct {
+	opt   Options
+	names []string
+	seqs  [][]byte
+
+	cumLens []int // cumulative numbers of start positions of eligible references
+	refs    []int // indexes of eligible references
+
+	rng *rand.Rand
+	n   int // number of simulated reads
+}
+
+// NewSimulator creates a Simulator. Only references not shorter than
+// the fragment length are used.
+func NewSimulator(names []string, seqs [][]byte, opt *Options) (*Simulator, error) {
+	if opt.Length < 1 {
+		return nil, fmt.Errorf("simulate: invalid fragment length: %d", opt.Length)
+	}
+	for _, r := range []float64{opt.SubRate, opt.InsRate, opt.DelRate} {
+		if r < 0 || r >= 1 {
+			return nil, ErrInvalidRates
+		}
+	}
+	if opt.SubRate+opt.InsRate+opt.DelRate >= 1 {
+		return nil, ErrInvalidRates
+	}
+
+	s := &Simulator{opt: *opt, names: names, seqs: seqs, rng: rand.New(rand.NewSource(opt.Seed))}
+	var sum int
+	for i, seq := range seqs {
+		if len(seq) < opt.Length {
+			continue
+		}
+		sum += len(seq) - opt.Length + 1
+		s.cumLens = append(s.cumLens, sum)
+		s.refs = append(s.refs, i)
+	}
+	if sum == 0 {
+		return nil, ErrNoReferences
+	}
+	return s, nil
+}
+
+// Next simulates a read.
+func (s *Simulator) Next() *Read {
+	s.n++
+	x := s.rng.Intn(s.cumLens[len(s.cumLens)-1])
+	i := sort.SearchInts(s.cumLens, x+1)
+	ref := s.seqs[s.refs[i]]
+	start := x
+	if i > 0 {
+		start -= s.cumLens[i-1]
+	}
+
+	r := &Read{
+		Name:     s.opt.Prefix + strconv.Itoa(s.n),
+		RefName:  s.names[s.refs[i]],
+		RefStart: start,
+		RefEnd:   start + s.opt.Length,
+	}
+	r.Seq, r.Mutations = s.mutate(ref[r.RefStart:r.RefEnd], r.RefStart)
+	if s.opt.RevComp && s.rng.Intn(2) == 1 {
+		r.Rev = true
+		r.Seq = strobemers.ReverseComplement(r.Seq, nil)
+	}
+	return r
+}
+
+// mutate applies random mutations to a fragment starting at offset
+// in the reference.
+func (s *Simulator) mutate(frag []byte, offset int) ([]byte, []Mutation) {
+	opt := &s.opt
+	seq := make([]byte, 0, len(frag)+8)
+	var muts []Mutation
+	var u float64
+	var b byte
+	for i, ref := range frag {
+		u = s.rng.Float64()
+		switch {
+		case u < opt.DelRate:
+			muts = append(muts, Mutation{Type: Deletion, Pos: offset + i, Ref: ref})
+		case u < opt.DelRate+opt.InsRate:
+			b = bases[s.rng.Intn(4)]
+			muts = append(muts, Mutation{Type: Insertion, Pos: offset + i, Alt: b})
+			seq = append(seq, b, ref)
+		case u < opt.DelRate+opt.InsRate+opt.SubRate:
+			b = s.otherBase(ref)
+			muts = append(muts, Mutation{Type: Substitution, Pos: offset + i, Ref: ref, Alt: b})
+			seq = append(seq, b)
+		default:
+			seq = append(seq, ref)
+		}
+	}
+	return seq, muts
+}
+
+var bases = [4]byte{'A', 'C', 'G', 'T'}
+
+// otherBase returns a random base different from b.
+func (s *Simulator) otherBase(b byte) byte {
+	for {
+		c := bases[s.rng.Intn(4)]
+		if c != b && c != b+('a'-'A') && c+('a'-'A') != b {
+			return c
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package simulate
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func randomSeq(r *rand.Rand, n int) []byte {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = bases[r.Intn(4)]
+	}
+	return s
+}
+
+func TestSimulator(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	names := []string{"chr1", "chr2", "short"}
+	seqs := [][]byte{randomSeq(r, 20000), randomSeq(r, 10000), randomSeq(r, 100)}
+
+	opt := DefaultOptions
+	opt.SubRate, opt.InsRate, opt.DelRate = 0.05, 0.01, 0.01
+	opt.Length = 500
+	s, err := NewSimulator(names, seqs, &opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nSub, nIns, nDel, nRev, nBases int
+	for i := 0; i < 1000; i++ {
+		read := s.Next()
+		if read.RefName == "short" {
+			t.Fatalf("short reference should not be sampled")
+		}
+		ref := seqs[0]
+		if read.RefName == "chr2" {
+			ref = seqs[1]
+		}
+
+		// the truth is consistent with the sequence and mutations
+		subs := make(map[int]bool)
+		for _, m := range read.Mutations {
+			if m.Type == Substitution {
+				subs[m.Pos] = true
+			}
+		}
+		fwd := read.Forward()
+		truth := read.Truth()
+		if len(truth) != len(fwd) {
+			t.Fatalf("%s: length of truth %d != read length %d", read.Name, len(truth), len(fwd))
+		}
+		for j, p := range truth {
+			if p < 0 {
+				continue
+			}
+			if p < read.RefStart || p >= read.RefEnd || (fwd[j] == ref[p]) == subs[p] {
+				t.Fatalf("%s: base %d inconsistent with the truth", read.Name, j)
+			}
+		}
+
+		// headers can be parsed back
+		parsed, err := ParseHeader(read.Header())
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed.Seq = read.Seq
+		if !reflect.DeepEqual(parsed, read) {
+			t.Fatalf("header not parsed back: %s", read.Header())
+		}
+
+		sub, ins, del := read.Counts()
+		nSub += sub
+		nIns += ins
+		nDel += del
+		nBases += read.RefEnd - read.RefStart
+		if read.Rev {
+			nRev++
+		}
+	}
+	for _, c := range []struct {
+		n    int
+		rate float64
+	}{{nSub, opt.SubRate}, {nIns, opt.InsRate}, {nDel, opt.DelRate}, {nRev, 0.5}} {
+		total := nBases
+		if c.rate == 0.5 {
+			total = 1000
+		}
+		if math.Abs(float64(c.n)/float64(total)-c.rate) > c.rate*0.1 {
+			t.Errorf("observed rate %.4f, expected %.4f", float64(c.n)/float64(total), c.rate)
+		}
+	}
+
+	// reproducible
+	s1, _ := NewSimulator(names, seqs, &opt)
+	s2, _ := NewSimulator(names, seqs, &opt)
+	for i := 0; i < 10; i++ {
+		if !reflect.DeepEqual(s1.Next(), s2.Next()) {
+			t.Fatalf("simulation with the same seed is not reproducible")
+		}
+	}
+
+	opt.SubRate = 1
+	if _, err = NewSimulator(names, seqs, &opt); err != ErrInvalidRates {
+		t.Errorf("invalid rates should be rejected")
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	read := &Read{
+		Name:      "read_1",
+		RefName:   "chr1 Homo sapiens chromosome 1",
+		RefStart:  100,
+		RefEnd:    250,
+		Rev:       true,
+		Mutations: []Mutation{{Pos: 105, Type: Substitution, Ref: 'A', Alt: 'C'}},
+	}
+	parsed, err := ParseHeader(read.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, read) {
+		t.Errorf("header not parsed back: %s", read.Header())
+	}
+
+	for _, h := range []string{
+		"read_1 ref=chr1 start=100 end=250 strand=-",
+		"read_1 start=100 end=250 strand=- muts=-",
+		"read 1 ref=chr1 start=100 end=250 strand=- muts=-",
+		"read_1 ref= start=100 end=250 strand=- muts=-",
+	} {
+		if _, err = ParseHeader(h); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%s: expected ErrInvalidHeader, got: %v", h, err)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	opt := DefaultOptions
+	s, _ := NewSimulator([]string{"chr1"}, [][]byte{randomSeq(r, 1000)}, &opt)
+
+	fq, tsv := &bytes.Buffer{}, &bytes.Buffer{}
+	w := NewWriter(fq, FASTQ, tsv)
+	reads := make([]*Read, 5)
+	for i := range reads {
+		reads[i] = s.Next()
+		if err := w.Write(reads[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(fq.String()), "\n")
+	if len(lines) != 4*len(reads) {
+		t.Fatalf("unexpected number of FASTQ lines: %d", len(lines))
+	}
+	for i, read := range reads {
+		if lines[i*4] != "@"+read.Header() || lines[i*4+1] != string(read.Seq) || len(lines[i*4+3]) != len(read.Seq) {
+			t.Errorf("unexpected FASTQ record of %s", read.Name)
+		}
+	}
+
+	lines = strings.Split(strings.TrimSpace(tsv.String()), "\n")
+	if len(lines) != len(reads)+1 || len(strings.Split(lines[1], "\t")) != 9 {
+		t.Errorf("unexpected truth file:\n%s", tsv.String())
+	}
+
+	fa := &bytes.Buffer{}
+	w = NewWriter(fa, FASTA, nil)
+	w.SetLineWidth(60)
+	w.Write(reads[0])
+	w.Flush()
+	if n := strings.Count(fa.String(), "\n"); n != 1+(len(reads[0].Seq)+59)/60 {
+		t.Errorf("unexpected number of FASTA lines: %d", n)
+	}
+}
@@ -0,0 +1,108 @@
+package simulate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Format is the output format of reads.
+type Format int
+
+const (
+	// FASTA format
+	FASTA Format = iota
+	// FASTQ format, with a constant quality
+	FASTQ
+)
+
+// Writer writes simulated reads in FASTA/FASTQ format, with the origin and
+// mutations in headers, and optionally a TSV side file of them.
+type Writer struct {
+	w      *bufio.Writer
+	truth  *bufio.Writer
+	format Format
+	qual   byte
+
+	width int // line width of FASTA, 0 for no wrapping
+}
+
+// NewWriter creates a Writer. truth can be nil if the side file is not needed.
+func NewWriter(w io.Writer, format Format, truth io.Writer) *Writer {
+	wr := &Writer{w: bufio.NewWriter(w), format: format, qual: 'I'}
+	if truth != nil {
+		wr.truth = bufio.NewWriter(truth)
+		wr.truth.WriteString("read\tref\tstart\tend\tstrand\tnSub\tnIns\tnDel\tmuts\n")
+	}
+	return wr
+}
+
+// SetQuality sets the quality character of FASTQ records, default I.
+func (w *Writer) SetQuality(q byte) {
+	w.qual = q
+}
+
+// SetLineWidth sets the line width of FASTA sequences, 0 for no wrapping.
+func (w *Writer) SetLineWidth(width int) {
+	w.width = width
+}
+
+// Write writes a read.
+func (w *Writer) Write(r *Read) error {
+	var err error
+	switch w.format {
+	case FASTQ:
+		_, err = fmt.Fprintf(w.w, "@%s\n%s\n+\n", r.Header(), r.Seq)
+		if err != nil {
+			return err
+		}
+		for range r.Seq {
+			w.w.WriteByte(w.qual)
+		}
+		err = w.w.WriteByte('\n')
+	default:
+		_, err = fmt.Fprintf(w.w, ">%s\n", r.Header())
+		if err != nil {
+			return err
+		}
+		err = w.writeSeq(r.Seq)
+	}
+	if err != nil || w.truth == nil {
+		return err
+	}
+
+	strand := "+"
+	if r.Rev {
+		strand = "-"
+	}
+	sub, ins, del := r.Counts()
+	_, err = fmt.Fprintf(w.truth, "%s\t%s\t%d\t%d\t%s\t%d\t%d\t%d\t%s\n",
+		r.Name, r.RefName, r.RefStart, r.RefEnd, strand, sub, ins, del, r.mutationsString())
+	return err
+}
+
+func (w *Writer) writeSeq(seq []byte) error {
+	if w.width <= 0 {
+		w.w.Write(seq)
+		return w.w.WriteByte('\n')
+	}
+	for i := 0; i < len(seq); i += w.width {
+		j := i + w.width
+		if j > len(seq) {
+			j = len(seq)
+		}
+		w.w.Write(seq[i:j])
+		w.w.WriteByte('\n')
+	}
+	return nil
+}
+
+// Flush writes buffered data to the underlying writers.
+func (w *Writer) Flush() error {
+	if w.truth != nil {
+		if err := w.truth.Flush(); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
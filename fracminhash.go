@@ -0,0 +1,237 @@
+package strobemers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// FracMinHash is a scaled sketch (FracMinHash, as in sourmash) of
+// strobemers, keeping all hash values below 2^64/Scale. Hash values must
+// be uniformly distributed, i.e., computed with HashNtHashMixed.
+//
+// Sketches of different sizes are comparable as long as they share the
+// same Params and Scale.
+type FracMinHash struct {
+	Params Params
+	Scale  uint64
+
+	max    uint64 // maximum hash value kept
+	hashes map[uint64]struct{}
+}
+
+// NewFracMinHash creates an empty FracMinHash.
+func NewFracMinHash(p *Params, scale uint64) (*FracMinHash, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Hash != HashNtHashMixed {
+		return nil, ErrHashNotUniform
+	}
+	if scale < 1 {
+		return nil, fmt.Errorf("strobemers: invalid scale: %d", scale)
+	}
+	return &FracMinHash{
+		Params: *p,
+		Scale:  scale,
+		max:    math.MaxUint64 / scale,
+		hashes: make(map[uint64]struct{}, 1024),
+	}, nil
+}
+
+// Add adds strobemers of the positive strand of seq with hash values below
+// the threshold. Strobemers are not canonical, so sketches of sequences of
+// unknown orientation, e.g., reads, should be built with AddBothStrands.
+func (s *FracMinHash) Add(seq []byte) error {
+	return addStrobemers(seq, &s.Params, false, s.AddHash)
+}
+
+// AddBothStrands is like Add, but also adds strobemers of the reverse
+// complement of seq.
+func (s *FracMinHash) AddBothStrands(seq []byte) error {
+	return addStrobemers(seq, &s.Params, true, s.AddHash)
+}
+
+// AddHash adds a hash value, which is ignored if it's above the threshold.
+func (s *FracMinHash) AddHash(hash uint64) {
+	if hash <= s.max {
+		s.hashes[hash] = struct{}{}
+	}
+}
+
+// Len returns the number of hash values in the sketch.
+func (s *FracMinHash) Len() int {
+	return len(s.hashes)
+}
+
+// Hashes returns sorted hash values.
+func (s *FracMinHash) Hashes() []uint64 {
+	hashes := make([]uint64, 0, len(s.hashes))
+	for h := range s.hashes {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// Cardinality estimates the number of distinct strobemers.
+func (s *FracMinHash) Cardinality() float64 {
+	return float64(len(s.hashes)) * float64(s.Scale)
+}
+
+func (s *FracMinHash) check(o *FracMinHash) error {
+	if !s.Params.Equal(&o.Params) || s.Scale != o.Scale {
+		return ErrSketchMismatch
+	}
+	return nil
+}
+
+// Merge adds all hash values of another sketch, e.g., one built in
+// another goroutine from other sequences.
+func (s *FracMinHash) Merge(o *FracMinHash) error {
+	if err := s.check(o); err != nil {
+		return err
+	}
+	for h := range o.hashes {
+		s.hashes[h] = struct{}{}
+	}
+	return nil
+}
+
+// Intersection returns the number of shared hash values.
+func (s *FracMinHash) Intersection(o *FracMinHash) (int, error) {
+	if err := s.check(o); err != nil {
+		return 0, err
+	}
+	return Intersection(s.hashes, o.hashes), nil
+}
+
+// Containment estimates the fraction of strobemers of s contained in o.
+func (s *FracMinHash) Containment(o *FracMinHash) (float64, error) {
+	inter, err := s.Intersection(o)
+	if err != nil || len(s.hashes) == 0 {
+		return 0, err
+	}
+	return float64(inter) / float64(len(s.hashes)), nil
+}
+
+// Jaccard estimates the Jaccard index of strobemers of two sketches.
+func (s *FracMinHash) Jaccard(o *FracMinHash) (float64, error) {
+	inter, err := s.Intersection(o)
+	if err != nil {
+		return 0, err
+	}
+	union := len(s.hashes) + len(o.hashes) - inter
+	if union == 0 {
+		return 0, nil
+	}
+	return float64(inter) / float64(union), nil
+}
+
+// FracMinHash file format (little-endian):
+//
+//	magic          [8]byte   "STROBFMH"
+//	format version uint32
+//	scheme, hash, shrink, sampling 4 x uint8
+//	n, l, wMin, wMax               4 x uint32
+//	prime          uint64
+//	sampleW, syncmerS, syncmerT    3 x uint32
+//	strobe syncmer s               uint32
+//	scale          uint64
+//	#hashes        uint64
+//	hashes         #hashes x uint64, sorted
+
+// FracMinHashFormatVersion is the version of the FracMinHash file format.
+const FracMinHashFormatVersion uint32 = 2
+
+var fracMinHashMagic = [8]byte{'S', 'T', 'R', 'O', 'B', 'F', 'M', 'H'}
+
+// WriteTo writes the sketch in the binary format.
+func (s *FracMinHash) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	var err error
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(cw, binary.LittleEndian, data)
+		}
+	}
+	write(fracMinHashMagic)
+	write(FracMinHashFormatVersion)
+	write(newParamsHeader(&s.Params))
+	write(s.Scale)
+	write(uint64(len(s.hashes)))
+	if err != nil {
+		return cw.n, err
+	}
+
+	buf := make([]byte, 8)
+	for _, h := range s.Hashes() {
+		binary.LittleEndian.PutUint64(buf, h)
+		if _, err = cw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, bw.Flush()
+}
+
+// ReadFracMinHash reads a sketch written by WriteTo.
+func ReadFracMinHash(r io.Reader) (*FracMinHash, error) {
+	br := bufio.NewReader(r)
+
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(br, binary.LittleEndian, data)
+		}
+	}
+	var magic [8]byte
+	var version uint32
+	var hdr paramsHeader
+	var scale, n uint64
+	read(&magic)
+	read(&version)
+	read(&hdr)
+	read(&scale)
+	read(&n)
+	if err != nil {
+		return nil, sketchReadError(err)
+	}
+	if magic != fracMinHashMagic {
+		return nil, fmt.Errorf("%w: bad magic number", ErrInvalidSketchFile)
+	}
+	if version != FracMinHashFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version: %d", ErrInvalidSketchFile, version)
+	}
+
+	p := hdr.params()
+	s, err := NewFracMinHash(&p, scale)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSketchFile, err)
+	}
+
+	buf := make([]byte, 8)
+	var h uint64
+	for i := uint64(0); i < n; i++ {
+		if _, err = io.ReadFull(br, buf); err != nil {
+			return nil, sketchReadError(err)
+		}
+		h = binary.LittleEndian.Uint64(buf)
+		if h > s.max {
+			return nil, fmt.Errorf("%w: hash value above the threshold", ErrInvalidSketchFile)
+		}
+		s.hashes[h] = struct{}{}
+	}
+	return s, nil
+}
+
+func sketchReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of file", ErrInvalidSketchFile)
+	}
+	return err
+}
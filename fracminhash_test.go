@@ -0,0 +1,120 @@
+package strobemers
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestHashNtHashMixed(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	q := *p
+	q.Hash = HashNtHashMixed
+
+	it1, _ := NewIterator(&seqs[0], p)
+	it2, _ := NewIterator(&seqs[0], &q)
+	if h := it2.Params().Hash; h != HashNtHashMixed {
+		t.Errorf("unexpected hash function: %s", h)
+	}
+	var h1, h2 uint64
+	var ok1, ok2 bool
+	var n, low int
+	for {
+		h1, ok1 = it1.Next()
+		h2, ok2 = it2.Next()
+		if ok1 != ok2 {
+			t.Fatalf("different numbers of strobemers")
+		}
+		if !ok1 {
+			break
+		}
+		if h2 != hash64(h1) {
+			t.Fatalf("unexpected mixed hash value")
+		}
+		n++
+		if h2 < math.MaxUint64/4 {
+			low++
+		}
+	}
+	if f := float64(low) / float64(n); math.Abs(f-0.25) > 0.05 {
+		t.Errorf("mixed hash values are not uniform: %.3f of them in the lowest quarter", f)
+	}
+}
+
+func TestFracMinHash(t *testing.T) {
+	r := rand.New(rand.NewSource(21))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	if _, err := NewFracMinHash(p, 10); err != ErrHashNotUniform {
+		t.Errorf("non-uniform hash function should be rejected")
+	}
+	p.Hash = HashNtHashMixed
+
+	seqA := randomSeq(r, 200000)
+	seqB := append(append([]byte{}, seqA[:100000]...), randomSeq(r, 100000)...)
+
+	var scale uint64 = 50
+	a, err := NewFracMinHash(p, scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewFracMinHash(p, scale)
+	a.Add(seqA)
+	b.Add(seqB)
+
+	setA, _ := NewHashSet([][]byte{seqA}, p)
+	setB, _ := NewHashSet([][]byte{seqB}, p)
+	if c := a.Cardinality(); math.Abs(c-float64(len(setA)))/float64(len(setA)) > 0.1 {
+		t.Errorf("estimated cardinality %.0f, real %d", c, len(setA))
+	}
+	j, _ := a.Jaccard(b)
+	if real := Jaccard(setA, setB); math.Abs(j-real) > 0.05 {
+		t.Errorf("estimated Jaccard %.4f, real %.4f", j, real)
+	}
+	c, _ := a.Containment(b)
+	if real := Containment(setA, setB); math.Abs(c-real) > 0.05 {
+		t.Errorf("estimated containment %.4f, real %.4f", c, real)
+	}
+
+	// merging
+	m1, _ := NewFracMinHash(p, scale)
+	m1.Add(seqA[:100000])
+	m2, _ := NewFracMinHash(p, scale)
+	m2.Add(seqA[100000:])
+	m1.Merge(m2)
+	if c, _ = a.Containment(m1); c < 0.99 {
+		t.Errorf("merged sketch should contain almost all of the whole one: %.4f", c)
+	}
+
+	// both strands
+	both, _ := NewFracMinHash(p, scale)
+	both.AddBothStrands(seqA)
+	m1, _ = NewFracMinHash(p, scale)
+	m1.Add(seqA)
+	m1.Add(ReverseComplement(seqA, nil))
+	if !reflect.DeepEqual(both.Hashes(), m1.Hashes()) || both.Len() <= a.Len() {
+		t.Errorf("unexpected sketch of both strands")
+	}
+
+	other, _ := NewFracMinHash(p, scale*2)
+	if err = a.Merge(other); err != ErrSketchMismatch {
+		t.Errorf("sketches of different scales should not be merged")
+	}
+
+	// serialization
+	buf := &bytes.Buffer{}
+	if _, err = a.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	a2, err := ReadFracMinHash(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a2.Params.Equal(&a.Params) || a2.Scale != a.Scale || !reflect.DeepEqual(a2.Hashes(), a.Hashes()) {
+		t.Errorf("sketch changed after serialization")
+	}
+	if _, err = ReadFracMinHash(bytes.NewReader(buf.Bytes()[:buf.Len()-3])); err == nil {
+		t.Errorf("truncated file should be rejected")
+	}
+}
@@ -0,0 +1,119 @@
+package strobemers
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// MinHLLPrecision and MaxHLLPrecision are the range of HyperLogLog precision.
+const (
+	MinHLLPrecision = 4
+	MaxHLLPrecision = 18
+)
+
+// HyperLogLog estimates the number of distinct strobemers with 2^Precision
+// registers of one byte, with a relative standard error of about
+// 1.04/sqrt(2^Precision). Strobemer hash values are mixed with hash64, so
+// any HashFunc can be used.
+type HyperLogLog struct {
+	Params    Params
+	Precision int
+
+	registers []uint8
+}
+
+// NewHyperLogLog creates an empty HyperLogLog of the given precision,
+// ranging from 4 to 18. Precision 14 uses 16 KB with an error of 0.8%.
+func NewHyperLogLog(p *Params, precision int) (*HyperLogLog, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if precision < MinHLLPrecision || precision > MaxHLLPrecision {
+		return nil, fmt.Errorf("strobemers: invalid HyperLogLog precision: %d", precision)
+	}
+	return &HyperLogLog{
+		Params:    *p,
+		Precision: precision,
+		registers: make([]uint8, 1<<uint(precision)),
+	}, nil
+}
+
+// Add updates registers with strobemers of the positive strand of seq.
+func (s *HyperLogLog) Add(seq []byte) error {
+	return addStrobemers(seq, &s.Params, false, s.AddHash)
+}
+
+// AddBothStrands updates registers with strobemers of both strands of seq,
+// for counting distinct strobemers of reads from either strand.
+func (s *HyperLogLog) AddBothStrands(seq []byte) error {
+	return addStrobemers(seq, &s.Params, true, s.AddHash)
+}
+
+// AddHash adds a strobemer hash value.
+func (s *HyperLogLog) AddHash(hash uint64) {
+	h := hash64(hash)
+	p := uint(s.Precision)
+	i := h >> (64 - p)                                   // the first p bits for the register
+	rho := uint8(bits.LeadingZeros64(h<<p|1<<(p-1))) + 1 // position of the first 1 in the rest
+	if rho > s.registers[i] {
+		s.registers[i] = rho
+	}
+}
+
+// Merge merges another HyperLogLog, e.g., one filled in another goroutine,
+// the result is the HyperLogLog of the union.
+func (s *HyperLogLog) Merge(o *HyperLogLog) error {
+	if !s.Params.Equal(&o.Params) || s.Precision != o.Precision {
+		return ErrSketchMismatch
+	}
+	for i, r := range o.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Registers returns the registers.
+func (s *HyperLogLog) Registers() []uint8 {
+	return s.registers
+}
+
+// Reset clears all registers.
+func (s *HyperLogLog) Reset() {
+	for i := range s.registers {
+		s.registers[i] = 0
+	}
+}
+
+// Cardinality estimates the number of distinct strobemers. Linear counting
+// is used for small cardinalities.
+func (s *HyperLogLog) Cardinality() float64 {
+	m := float64(len(s.registers))
+	var sum float64
+	var zeros int
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var alpha float64
+	switch len(s.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
+	}
+	return e
+}
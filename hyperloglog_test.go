@@ -0,0 +1,77 @@
+package strobemers
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	r := rand.New(rand.NewSource(45))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	if _, err := NewHyperLogLog(p, MaxHLLPrecision+1); err == nil {
+		t.Errorf("invalid precision should be rejected")
+	}
+
+	seqA := randomSeq(r, 300000)
+	seqB := append(append([]byte{}, seqA[:100000]...), randomSeq(r, 200000)...)
+
+	exact, err := NewHashSet([][]byte{seqA, seqB}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, precision := range []int{10, 14} {
+		all, err := NewHyperLogLog(p, precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, _ := NewHyperLogLog(p, precision)
+		b, _ := NewHyperLogLog(p, precision)
+		for _, seq := range [][]byte{seqA, seqB} {
+			if err = all.Add(seq); err != nil {
+				t.Fatal(err)
+			}
+		}
+		a.Add(seqA)
+		b.Add(seqB)
+		if err = a.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+		if a.Cardinality() != all.Cardinality() {
+			t.Errorf("precision %d: merged estimate %.0f != %.0f", precision, a.Cardinality(), all.Cardinality())
+		}
+
+		se := 1.04 / math.Sqrt(float64(int(1)<<uint(precision)))
+		if e := math.Abs(all.Cardinality()-float64(len(exact))) / float64(len(exact)); e > 4*se {
+			t.Errorf("precision %d: estimate %.0f, exact %d", precision, all.Cardinality(), len(exact))
+		}
+	}
+
+	// both strands
+	both, _ := NewHyperLogLog(p, 14)
+	both.AddBothStrands(seqA)
+	m, _ := NewHyperLogLog(p, 14)
+	m.Add(seqA)
+	m.Add(ReverseComplement(seqA, nil))
+	if !reflect.DeepEqual(both.Registers(), m.Registers()) {
+		t.Errorf("unexpected registers of both strands")
+	}
+
+	// small cardinalities
+	s, _ := NewHyperLogLog(p, 14)
+	for i := 0; i < 1000; i++ {
+		s.AddHash(uint64(i))
+	}
+	if c := s.Cardinality(); math.Abs(c-1000) > 20 {
+		t.Errorf("unexpected estimate of 1000 values: %.0f", c)
+	}
+
+	q := *p
+	q.Hash = HashNtHashMixed
+	o, _ := NewHyperLogLog(&q, 14)
+	if err := s.Merge(o); err != ErrSketchMismatch {
+		t.Errorf("sketches with different params should not be merged")
+	}
+}
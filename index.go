@@ -0,0 +1,239 @@
+package strobemers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Strand is the strand mode of an index.
+type Strand uint8
+
+const (
+	// StrandForward means only strobemers of the positive strand are indexed,
+	// queries should be searched on both strands.
+	StrandForward Strand = iota + 1
+)
+
+// Occurrence is a location of a strobemer in the references.
+// The size is fixed (16 bytes) so that it can be memory-mapped directly.
+type Occurrence struct {
+	Ref uint32    // reference ID, i.e., the index in RefNames
+	Pos [3]uint32 // 0-based positions of strobes, Pos[2] is 0 for order 2
+}
+
+// Index is a strobemer index of reference sequences.
+// Hash values are sorted, and occurrences of hashes[i] are
+// occs[offsets[i]:offsets[i+1]].
+type Index struct {
+	Params  Params
+	Strand  Strand
+	Version string // version of the package creating the index
+
+	RefNames []string // names of references
+	RefLens  []int    // lengths of references
+
+	Filter RepeatFilter // filter of repetitive strobemers
+
+	hashes  []uint64     // sorted distinct hash values
+	offsets []uint64     // offsets of occurrences, len(offsets) = len(hashes) + 1
+	flags   []uint8      // flags of hashes, len(flags) = len(hashes)
+	occs    []Occurrence // occurrences
+
+	mmap []byte // memory-mapped data, nil for an in-memory index
+}
+
+// NumHashes returns the number of distinct strobemers.
+func (idx *Index) NumHashes() int {
+	return len(idx.hashes)
+}
+
+// NumOccurrences returns the number of all strobemer occurrences.
+func (idx *Index) NumOccurrences() int {
+	return len(idx.occs)
+}
+
+// NumRefs returns the number of reference sequences.
+func (idx *Index) NumRefs() int {
+	return len(idx.RefNames)
+}
+
+// Lookup returns all occurrences of a strobemer. The returned slice
+// is shared with the index and should not be modified.
+func (idx *Index) Lookup(hash uint64) []Occurrence {
+	i := idx.search(hash)
+	if i < 0 {
+		return nil
+	}
+	return idx.occs[idx.offsets[i]:idx.offsets[i+1]]
+}
+
+// search returns the index of a hash value, or -1 if not found.
+func (idx *Index) search(hash uint64) int {
+	hashes := idx.hashes
+	i, j := 0, len(hashes)
+	var h int
+	for i < j {
+		h = int(uint(i+j) >> 1)
+		if hashes[h] < hash {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i < len(hashes) && hashes[i] == hash {
+		return i
+	}
+	return -1
+}
+
+// CheckParams checks whether the index is compatible with the parameters
+// of a query iterator.
+func (idx *Index) CheckParams(p *Params) error {
+	if !idx.Params.Equal(p) {
+		return paramsMismatchError(&idx.Params, p)
+	}
+	return nil
+}
+
+func paramsMismatchError(index *Params, query *Params) error {
+	return fmt.Errorf("%w: index: %s, prime: %d, shrink: %v, hash: %s; query: %s, prime: %d, shrink: %v, hash: %s",
+		ErrParamsMismatch,
+		index, index.Prime, index.Shrink, index.Hash,
+		query, query.Prime, query.Shrink, query.Hash)
+}
+
+// Close releases the memory-mapped file, the index should not be used after that.
+func (idx *Index) Close() error {
+	if idx.mmap == nil {
+		return nil
+	}
+	err := munmapFile(idx.mmap)
+	idx.mmap = nil
+	idx.hashes, idx.offsets, idx.flags, idx.occs = nil, nil, nil, nil
+	return err
+}
+
+// ------------------------------------------------------------------------
+
+// IndexBuilder builds an Index from reference sequences.
+type IndexBuilder struct {
+	params Params
+
+	names []string
+	lens  []int
+
+	records []indexRecord
+
+	filter RepeatFilter
+}
+
+type indexRecord struct {
+	hash uint64
+	occ  Occurrence
+}
+
+type indexRecords []indexRecord
+
+func (l indexRecords) Len() int { return len(l) }
+func (l indexRecords) Less(i int, j int) bool {
+	a, b := &l[i], &l[j]
+	if a.hash != b.hash {
+		return a.hash < b.hash
+	}
+	if a.occ.Ref != b.occ.Ref {
+		return a.occ.Ref < b.occ.Ref
+	}
+	return a.occ.Pos[0] < b.occ.Pos[0]
+}
+func (l indexRecords) Swap(i int, j int) { l[i], l[j] = l[j], l[i] }
+
+// NewIndexBuilder creates an IndexBuilder.
+func NewIndexBuilder(p *Params) (*IndexBuilder, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &IndexBuilder{
+		params:  *p,
+		names:   make([]string, 0, 8),
+		lens:    make([]int, 0, 8),
+		records: make([]indexRecord, 0, 1024),
+	}, nil
+}
+
+// Add computes strobemers of a reference sequence and adds them to the index.
+// Sequences too short to produce any strobemer are recorded without strobemers.
+func (b *IndexBuilder) Add(name string, seq []byte) error {
+	if uint64(len(b.names)) >= math.MaxUint32 {
+		return ErrTooManyReferences
+	}
+	if uint64(len(seq)) > math.MaxUint32 {
+		return ErrSequenceTooLong
+	}
+	ref := uint32(len(b.names))
+	b.names = append(b.names, name)
+	b.lens = append(b.lens, len(seq))
+
+	iter, err := newIteratorIfLongEnough(&seq, &b.params)
+	if err != nil || iter == nil {
+		return err
+	}
+
+	var hash uint64
+	var ok bool
+	var locs []int
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+		locs = iter.Indexes()
+		b.records = append(b.records, indexRecord{
+			hash: hash,
+			occ: Occurrence{
+				Ref: ref,
+				Pos: [3]uint32{uint32(locs[0]), uint32(locs[1]), uint32(locs[2])},
+			},
+		})
+	}
+	return nil
+}
+
+// Build sorts all strobemers, filters repetitive ones if a RepeatFilter
+// is set, and returns the Index.
+// The builder should not be used after calling Build.
+func (b *IndexBuilder) Build() *Index {
+	records := b.records
+	sort.Sort(indexRecords(records))
+
+	var n int
+	for i := range records {
+		if i == 0 || records[i].hash != records[i-1].hash {
+			n++
+		}
+	}
+
+	idx := &Index{
+		Params:   b.params,
+		Strand:   StrandForward,
+		Version:  Version,
+		RefNames: b.names,
+		RefLens:  b.lens,
+		hashes:   make([]uint64, 0, n),
+		offsets:  make([]uint64, 0, n+1),
+		occs:     make([]Occurrence, len(records)),
+	}
+	for i := range records {
+		if i == 0 || records[i].hash != records[i-1].hash {
+			idx.hashes = append(idx.hashes, records[i].hash)
+			idx.offsets = append(idx.offsets, uint64(i))
+		}
+		idx.occs[i] = records[i].occ
+	}
+	idx.offsets = append(idx.offsets, uint64(len(records)))
+
+	b.records = nil
+	idx.applyRepeatFilter(&b.filter)
+	return idx
+}
@@ -0,0 +1,138 @@
+package strobemers
+
+import (
+	"math"
+	"sort"
+)
+
+// flags of hashes in an index
+const (
+	flagRepetitive uint8 = 1 << iota // occurrences of the hash exceed the threshold
+)
+
+// RepeatFilter decides which strobemers are repetitive in an index,
+// e.g., those from ALU elements and satellites.
+type RepeatFilter struct {
+	// MaxOcc is the maximum number of occurrences of a strobemer,
+	// 0 for no limit.
+	MaxOcc int
+	// TopFrac masks the given fraction of most frequent distinct strobemers,
+	// e.g., 0.0002 for the top 0.02%. 0 for no masking.
+	TopFrac float64
+	// Soft only flags repetitive strobemers instead of removing them,
+	// so queries can skip or down-weight them.
+	Soft bool
+}
+
+// Enabled tells whether any filtering criterion is set.
+func (f *RepeatFilter) Enabled() bool {
+	return f.MaxOcc > 0 || f.TopFrac > 0
+}
+
+// SetRepeatFilter sets the filter of repetitive strobemers applied in Build.
+func (b *IndexBuilder) SetRepeatFilter(f RepeatFilter) {
+	b.filter = f
+}
+
+// IsRepetitive tells whether a strobemer is flagged as repetitive.
+// Only softly filtered indexes contain repetitive strobemers.
+func (idx *Index) IsRepetitive(hash uint64) bool {
+	i := idx.search(hash)
+	return i >= 0 && idx.flags[i]&flagRepetitive > 0
+}
+
+// LookupWithFlag returns all occurrences of a strobemer and whether it's repetitive.
+// The returned slice is shared with the index and should not be modified.
+func (idx *Index) LookupWithFlag(hash uint64) ([]Occurrence, bool) {
+	i := idx.search(hash)
+	if i < 0 {
+		return nil, false
+	}
+	return idx.occs[idx.offsets[i]:idx.offsets[i+1]], idx.flags[i]&flagRepetitive > 0
+}
+
+// NumRepetitive returns the number of strobemers flagged as repetitive.
+func (idx *Index) NumRepetitive() int {
+	var n int
+	for _, f := range idx.flags {
+		if f&flagRepetitive > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// RepeatThreshold returns the maximum number of occurrences allowed
+// for a non-repetitive strobemer, computed from the filter and the
+// distribution of occurrences. It returns math.MaxInt64 for no limit.
+func (idx *Index) RepeatThreshold(f *RepeatFilter) int {
+	threshold := math.MaxInt64
+	if f.MaxOcc > 0 {
+		threshold = f.MaxOcc
+	}
+	if f.TopFrac <= 0 || len(idx.hashes) == 0 {
+		return threshold
+	}
+
+	k := int(f.TopFrac * float64(len(idx.hashes)))
+	if k <= 0 {
+		return threshold
+	}
+	if k >= len(idx.hashes) {
+		k = len(idx.hashes) - 1
+	}
+	counts := make([]int, len(idx.hashes))
+	for i := range idx.hashes {
+		counts[i] = int(idx.offsets[i+1] - idx.offsets[i])
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+
+	// hashes sharing the count of the k-th most frequent one are all kept
+	if counts[k] < threshold {
+		threshold = counts[k]
+	}
+	return threshold
+}
+
+// applyRepeatFilter flags or removes repetitive strobemers.
+func (idx *Index) applyRepeatFilter(f *RepeatFilter) {
+	idx.Filter = *f
+	if idx.flags == nil {
+		idx.flags = make([]uint8, len(idx.hashes))
+	}
+	if !f.Enabled() {
+		return
+	}
+
+	threshold := uint64(idx.RepeatThreshold(f))
+
+	if f.Soft {
+		for i := range idx.hashes {
+			if idx.offsets[i+1]-idx.offsets[i] > threshold {
+				idx.flags[i] |= flagRepetitive
+			}
+		}
+		return
+	}
+
+	// remove them in place
+	var j int       // index of kept hashes
+	var o uint64    // offset of kept occurrences
+	var s, e uint64 // range of occurrences of a hash
+	for i := range idx.hashes {
+		s, e = idx.offsets[i], idx.offsets[i+1]
+		if e-s > threshold {
+			continue
+		}
+		idx.hashes[j] = idx.hashes[i]
+		idx.flags[j] = idx.flags[i]
+		idx.offsets[j] = o
+		copy(idx.occs[o:], idx.occs[s:e])
+		o += e - s
+		j++
+	}
+	idx.hashes = idx.hashes[:j]
+	idx.flags = idx.flags[:j]
+	idx.offsets = append(idx.offsets[:j], o)
+	idx.occs = idx.occs[:o]
+}
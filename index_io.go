@@ -0,0 +1,566 @@
+package strobemers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"unsafe"
+)
+
+// Index file format (little-endian):
+//
+//     magic            [8]byte   "STROBIDX"
+//     format version   uint32
+//     package version  uint32 (length) + bytes
+//     scheme, hash, strand, shrink   4 x uint8
+//     n, l, wMin, wMax 4 x uint32
+//     prime            uint64
+//     filter: maxOcc   uint64
+//     filter: topFrac  float64
+//     filter: soft     uint8
+//     sampling         uint8
+//     sampleW, syncmerS, syncmerT  3 x uint32
+//     strobe syncmer s uint32
+//     #references      uint64
+//       name           uint32 (length) + bytes
+//       length         uint64
+//     #hashes          uint64
+//     #occurrences     uint64
+//     padding          zero bytes to align to 8 bytes
+//     hashes           #hashes x uint64
+//     offsets          (#hashes+1) x uint64
+//     flags            #hashes x uint8
+//     padding          zero bytes to align to 8 bytes
+//     occurrences      #occurrences x (4 x uint32)
+//
+// The arrays are 8-byte aligned, so a memory-mapped file can be used
+// directly without copying.
+
+// IndexFormatVersion is the version of the index file format.
+const IndexFormatVersion uint32 = 4
+
+var indexMagic = [8]byte{'S', 'T', 'R', 'O', 'B', 'I', 'D', 'X'}
+
+// maxIndexArrayLen limits the array sizes when reading a corrupted file.
+const maxIndexArrayLen = 1 << 40
+
+// indexReadChunk is the number of array elements allocated at a time when
+// reading an index of unknown size.
+const indexReadChunk = 1 << 20
+
+const sizeOccurrence = int(unsafe.Sizeof(Occurrence{}))
+
+var nativeLittleEndian bool
+
+func init() {
+	x := uint16(1)
+	nativeLittleEndian = *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+type indexHeader struct {
+	Scheme uint8
+	Hash   uint8
+	Strand uint8
+	Shrink uint8
+	N      uint32
+	L      uint32
+	WMin   uint32
+	WMax   uint32
+	Prime  uint64
+
+	MaxOcc  uint64
+	TopFrac float64
+	Soft    uint8
+
+	Sampling uint8
+	SampleW  uint32
+	SyncmerS uint32
+	SyncmerT uint32
+	StrobeS  uint32
+}
+
+// WriteTo writes the index to w in the binary index format.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriterSize(w, 1<<16)
+	cw := &countingWriter{w: bw}
+
+	p := &idx.Params
+	var shrink, soft uint8
+	if p.Shrink {
+		shrink = 1
+	}
+	if idx.Filter.Soft {
+		soft = 1
+	}
+	hdr := indexHeader{
+		Scheme: uint8(p.Scheme),
+		Hash:   uint8(p.Hash),
+		Strand: uint8(idx.Strand),
+		Shrink: shrink,
+		N:      uint32(p.N),
+		L:      uint32(p.L),
+		WMin:   uint32(p.WMin),
+		WMax:   uint32(p.WMax),
+		Prime:  p.Prime,
+
+		MaxOcc:  uint64(idx.Filter.MaxOcc),
+		TopFrac: idx.Filter.TopFrac,
+		Soft:    soft,
+
+		Sampling: uint8(p.Sampling),
+		SampleW:  uint32(p.SampleW),
+		SyncmerS: uint32(p.SyncmerS),
+		SyncmerT: uint32(p.SyncmerT),
+		StrobeS:  uint32(p.StrobeSyncmerS),
+	}
+
+	var err error
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(cw, binary.LittleEndian, data)
+		}
+	}
+	writeString := func(s string) {
+		write(uint32(len(s)))
+		if err == nil {
+			_, err = io.WriteString(cw, s)
+		}
+	}
+
+	write(indexMagic)
+	write(IndexFormatVersion)
+	writeString(Version)
+	write(hdr)
+	write(uint64(len(idx.RefNames)))
+	for i, name := range idx.RefNames {
+		writeString(name)
+		write(uint64(idx.RefLens[i]))
+	}
+	write(uint64(len(idx.hashes)))
+	write(uint64(len(idx.occs)))
+	if err == nil {
+		_, err = cw.Write(make([]byte, padding8(cw.n)))
+	}
+	if err != nil {
+		return cw.n, err
+	}
+
+	buf := make([]byte, 8)
+	for _, v := range idx.hashes {
+		binary.LittleEndian.PutUint64(buf, v)
+		if _, err = cw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+	for _, v := range idx.offsets {
+		binary.LittleEndian.PutUint64(buf, v)
+		if _, err = cw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+	if _, err = cw.Write(idx.flags); err != nil {
+		return cw.n, err
+	}
+	if _, err = cw.Write(make([]byte, padding8(cw.n))); err != nil {
+		return cw.n, err
+	}
+	buf = make([]byte, sizeOccurrence)
+	for i := range idx.occs {
+		encodeOccurrence(buf, &idx.occs[i])
+		if _, err = cw.Write(buf); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, bw.Flush()
+}
+
+// WriteToFile writes the index to a file.
+func (idx *Index) WriteToFile(file string) error {
+	fh, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	_, err = idx.WriteTo(fh)
+	if err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// ReadIndex reads an index from r. If p is not nil, the parameters of
+// the index are checked against it, and ErrParamsMismatch is returned
+// when they differ.
+//
+// Array sizes in the header are checked against the size of r if it's an
+// io.Seeker, e.g., a file. Otherwise, arrays are allocated in chunks while
+// reading, so a corrupted header can not cause a huge allocation.
+func ReadIndex(r io.Reader, p *Params) (*Index, error) {
+	size := remainingSize(r)
+	cr := &countingReader{r: bufio.NewReaderSize(r, 1<<16)}
+	idx, nHashes, nOccs, err := readIndexHeader(cr, p)
+	if err != nil {
+		return nil, err
+	}
+	if nHashes > maxIndexArrayLen || nOccs > maxIndexArrayLen {
+		return nil, fmt.Errorf("%w: array too large", ErrInvalidIndexFile)
+	}
+	chunk := uint64(indexReadChunk)
+	if size >= 0 {
+		if _, _, end := indexLayout(cr.n, nHashes, nOccs); end > size {
+			return nil, fmt.Errorf("%w: unexpected file size", ErrInvalidIndexFile)
+		}
+		chunk = maxIndexArrayLen
+	}
+
+	if _, err = io.CopyN(io.Discard, cr, int64(padding8(cr.n))); err != nil {
+		return nil, indexReadError(err)
+	}
+
+	idx.hashes = make([]uint64, 0, minUint64(nHashes, chunk))
+	idx.offsets = make([]uint64, 0, minUint64(nHashes+1, chunk))
+	idx.flags = make([]uint8, 0, minUint64(nHashes, chunk))
+	idx.occs = make([]Occurrence, 0, minUint64(nOccs, chunk))
+	buf := make([]byte, 1<<16)
+	for i := uint64(0); i < nHashes; i++ {
+		if _, err = io.ReadFull(cr, buf[:8]); err != nil {
+			return nil, indexReadError(err)
+		}
+		idx.hashes = append(idx.hashes, binary.LittleEndian.Uint64(buf))
+	}
+	for i := uint64(0); i <= nHashes; i++ {
+		if _, err = io.ReadFull(cr, buf[:8]); err != nil {
+			return nil, indexReadError(err)
+		}
+		idx.offsets = append(idx.offsets, binary.LittleEndian.Uint64(buf))
+	}
+	var k uint64
+	for i := uint64(0); i < nHashes; i += k {
+		k = minUint64(nHashes-i, uint64(len(buf)))
+		if _, err = io.ReadFull(cr, buf[:k]); err != nil {
+			return nil, indexReadError(err)
+		}
+		idx.flags = append(idx.flags, buf[:k]...)
+	}
+	if _, err = io.CopyN(io.Discard, cr, int64(padding8(cr.n))); err != nil {
+		return nil, indexReadError(err)
+	}
+	var occ Occurrence
+	for i := uint64(0); i < nOccs; i++ {
+		if _, err = io.ReadFull(cr, buf[:sizeOccurrence]); err != nil {
+			return nil, indexReadError(err)
+		}
+		decodeOccurrence(buf, &occ)
+		idx.occs = append(idx.occs, occ)
+	}
+
+	if err = idx.checkOffsets(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// LoadIndex loads an index file. On Linux, the file is memory-mapped
+// and the hash and occurrence arrays are used without copying,
+// call Close to release it.
+// If p is not nil, the parameters of the index are checked against it,
+// and ErrParamsMismatch is returned when they differ.
+func LoadIndex(file string, p *Params) (*Index, error) {
+	if !nativeLittleEndian {
+		fh, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		return ReadIndex(fh, p)
+	}
+
+	data, err := mmapFile(file)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := parseIndex(data, p)
+	if err != nil {
+		munmapFile(data)
+		return nil, err
+	}
+	idx.mmap = data
+	return idx, nil
+}
+
+// parseIndex creates an index sharing the memory of data.
+func parseIndex(data []byte, p *Params) (*Index, error) {
+	cr := &countingReader{r: bytes.NewReader(data)}
+	idx, nHashes, nOccs, err := readIndexHeader(cr, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if nHashes > maxIndexArrayLen || nOccs > maxIndexArrayLen {
+		return nil, fmt.Errorf("%w: array too large", ErrInvalidIndexFile)
+	}
+	startFlags, startOccs, end := indexLayout(cr.n, nHashes, nOccs)
+	if int64(len(data)) != end {
+		return nil, fmt.Errorf("%w: unexpected file size", ErrInvalidIndexFile)
+	}
+
+	start := cr.n + int64(padding8(cr.n))
+	idx.hashes = bytesToUint64s(data[start:], int(nHashes))
+	start += int64(nHashes) * 8
+	idx.offsets = bytesToUint64s(data[start:], int(nHashes+1))
+	idx.flags = data[startFlags : startFlags+int64(nHashes) : startFlags+int64(nHashes)]
+	idx.occs = bytesToOccurrences(data[startOccs:], int(nOccs))
+
+	if err = idx.checkOffsets(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// indexLayout returns the offsets of the flags, the occurrences and the end
+// of an index file with the header ending at n.
+func indexLayout(n int64, nHashes uint64, nOccs uint64) (flags int64, occs int64, end int64) {
+	flags = n + int64(padding8(n)) + int64(nHashes)*8 + int64(nHashes+1)*8
+	occs = flags + int64(nHashes)
+	occs += int64(padding8(occs))
+	end = occs + int64(nOccs)*int64(sizeOccurrence)
+	return flags, occs, end
+}
+
+// remainingSize returns the number of bytes left in r if it's an io.Seeker,
+// or -1 otherwise.
+func remainingSize(r io.Reader) int64 {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return -1
+	}
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err = s.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+	return end - cur
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func readIndexHeader(cr *countingReader, p *Params) (*Index, uint64, uint64, error) {
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(cr, binary.LittleEndian, data)
+		}
+	}
+	readString := func() string {
+		var n uint32
+		read(&n)
+		if err != nil {
+			return ""
+		}
+		if n > 1<<20 {
+			err = fmt.Errorf("%w: string too long", ErrInvalidIndexFile)
+			return ""
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(cr, buf)
+		return string(buf)
+	}
+
+	var magic [8]byte
+	read(&magic)
+	if err != nil {
+		return nil, 0, 0, indexReadError(err)
+	}
+	if magic != indexMagic {
+		return nil, 0, 0, fmt.Errorf("%w: bad magic number", ErrInvalidIndexFile)
+	}
+	var version uint32
+	read(&version)
+	if err != nil {
+		return nil, 0, 0, indexReadError(err)
+	}
+	if version != IndexFormatVersion {
+		return nil, 0, 0, fmt.Errorf("%w: file: %d, supported: %d",
+			ErrIndexVersionMismatch, version, IndexFormatVersion)
+	}
+
+	idx := &Index{}
+	idx.Version = readString()
+
+	var hdr indexHeader
+	read(&hdr)
+	if err != nil {
+		return nil, 0, 0, indexReadError(err)
+	}
+	idx.Params = Params{
+		Scheme: Scheme(hdr.Scheme),
+		N:      int(hdr.N),
+		L:      int(hdr.L),
+		WMin:   int(hdr.WMin),
+		WMax:   int(hdr.WMax),
+		Prime:  hdr.Prime,
+		Shrink: hdr.Shrink != 0,
+		Hash:   HashFunc(hdr.Hash),
+
+		Sampling: Sampling(hdr.Sampling),
+		SampleW:  int(hdr.SampleW),
+		SyncmerS: int(hdr.SyncmerS),
+		SyncmerT: int(hdr.SyncmerT),
+
+		StrobeSyncmerS: int(hdr.StrobeS),
+	}
+	idx.Strand = Strand(hdr.Strand)
+	idx.Filter = RepeatFilter{
+		MaxOcc:  int(hdr.MaxOcc),
+		TopFrac: hdr.TopFrac,
+		Soft:    hdr.Soft != 0,
+	}
+	if err = idx.Params.Validate(); err != nil {
+		return nil, 0, 0, fmt.Errorf("%w: %s", ErrInvalidIndexFile, err)
+	}
+	if p != nil {
+		if err = idx.CheckParams(p); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	var nRefs uint64
+	read(&nRefs)
+	if err == nil && nRefs > maxIndexArrayLen {
+		err = fmt.Errorf("%w: too many references", ErrInvalidIndexFile)
+	}
+	if err != nil {
+		return nil, 0, 0, indexReadError(err)
+	}
+	idx.RefNames = make([]string, 0, minUint64(nRefs, 1024))
+	idx.RefLens = make([]int, 0, minUint64(nRefs, 1024))
+	var length uint64
+	for i := uint64(0); i < nRefs; i++ {
+		name := readString()
+		read(&length)
+		if err != nil {
+			return nil, 0, 0, indexReadError(err)
+		}
+		idx.RefNames = append(idx.RefNames, name)
+		idx.RefLens = append(idx.RefLens, int(length))
+	}
+
+	var nHashes, nOccs uint64
+	read(&nHashes)
+	read(&nOccs)
+	if err != nil {
+		return nil, 0, 0, indexReadError(err)
+	}
+	return idx, nHashes, nOccs, nil
+}
+
+// checkOffsets makes sure a loaded index would not cause out-of-range panics.
+func (idx *Index) checkOffsets() error {
+	n := uint64(len(idx.occs))
+	var prev uint64
+	for _, v := range idx.offsets {
+		if v < prev || v > n {
+			return fmt.Errorf("%w: bad offsets", ErrInvalidIndexFile)
+		}
+		prev = v
+	}
+	if len(idx.offsets) > 0 && idx.offsets[len(idx.offsets)-1] != n {
+		return fmt.Errorf("%w: bad offsets", ErrInvalidIndexFile)
+	}
+	nRefs := uint32(len(idx.RefNames))
+	for i := range idx.occs {
+		if idx.occs[i].Ref >= nRefs {
+			return fmt.Errorf("%w: bad reference ID", ErrInvalidIndexFile)
+		}
+	}
+	return nil
+}
+
+func indexReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of file", ErrInvalidIndexFile)
+	}
+	return err
+}
+
+func padding8(n int64) int {
+	return int((8 - n%8) % 8)
+}
+
+func encodeOccurrence(buf []byte, occ *Occurrence) {
+	binary.LittleEndian.PutUint32(buf, occ.Ref)
+	binary.LittleEndian.PutUint32(buf[4:], occ.Pos[0])
+	binary.LittleEndian.PutUint32(buf[8:], occ.Pos[1])
+	binary.LittleEndian.PutUint32(buf[12:], occ.Pos[2])
+}
+
+func decodeOccurrence(buf []byte, occ *Occurrence) {
+	occ.Ref = binary.LittleEndian.Uint32(buf)
+	occ.Pos[0] = binary.LittleEndian.Uint32(buf[4:])
+	occ.Pos[1] = binary.LittleEndian.Uint32(buf[8:])
+	occ.Pos[2] = binary.LittleEndian.Uint32(buf[12:])
+}
+
+// bytesToUint64s and bytesToOccurrences reinterpret the memory of data,
+// which must be 8-byte aligned and outlive the returned slices.
+func bytesToUint64s(data []byte, n int) []uint64 {
+	s := []uint64{}
+	if n == 0 {
+		return s
+	}
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&s))
+	sh.Data = uintptr(unsafe.Pointer(&data[0]))
+	sh.Len = n
+	sh.Cap = n
+	return s
+}
+
+func bytesToOccurrences(data []byte, n int) []Occurrence {
+	s := []Occurrence{}
+	if n == 0 {
+		return s
+	}
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&s))
+	sh.Data = uintptr(unsafe.Pointer(&data[0]))
+	sh.Len = n
+	sh.Cap = n
+	return s
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,103 @@
+package strobemers
+
+import (
+	"fmt"
+	"math"
+)
+
+// MergeIndexes merges two indexes built with identical parameters.
+// Reference IDs of a are kept, and those of b are shifted by a.NumRefs().
+// Repetitive strobemers are flagged again with the filter of a.
+//
+// Indexes with strobemers removed by a hard RepeatFilter are rejected,
+// as the counts of removed strobemers are unknown.
+func MergeIndexes(a, b *Index) (*Index, error) {
+	if err := a.CheckParams(&b.Params); err != nil {
+		return nil, err
+	}
+	if a.Strand != b.Strand {
+		return nil, fmt.Errorf("%w: strand mode: %d vs %d", ErrParamsMismatch, a.Strand, b.Strand)
+	}
+	if a.Filter != b.Filter {
+		return nil, fmt.Errorf("%w: repeat filter: %+v vs %+v", ErrParamsMismatch, a.Filter, b.Filter)
+	}
+	if (a.Filter.Enabled() && !a.Filter.Soft) || (b.Filter.Enabled() && !b.Filter.Soft) {
+		return nil, ErrIndexFiltered
+	}
+	if uint64(a.NumRefs())+uint64(b.NumRefs()) > math.MaxUint32 {
+		return nil, ErrTooManyReferences
+	}
+
+	shift := uint32(a.NumRefs())
+
+	idx := &Index{
+		Params:   a.Params,
+		Strand:   a.Strand,
+		Version:  Version,
+		RefNames: make([]string, 0, a.NumRefs()+b.NumRefs()),
+		RefLens:  make([]int, 0, a.NumRefs()+b.NumRefs()),
+		hashes:   make([]uint64, 0, len(a.hashes)+len(b.hashes)),
+		offsets:  make([]uint64, 0, len(a.hashes)+len(b.hashes)+1),
+		occs:     make([]Occurrence, 0, len(a.occs)+len(b.occs)),
+	}
+	idx.RefNames = append(append(idx.RefNames, a.RefNames...), b.RefNames...)
+	idx.RefLens = append(append(idx.RefLens, a.RefLens...), b.RefLens...)
+
+	appendB := func(j int) {
+		for _, occ := range b.occs[b.offsets[j]:b.offsets[j+1]] {
+			occ.Ref += shift
+			idx.occs = append(idx.occs, occ)
+		}
+	}
+
+	// merge two sorted hash lists
+	var i, j int
+	for i < len(a.hashes) || j < len(b.hashes) {
+		idx.offsets = append(idx.offsets, uint64(len(idx.occs)))
+		switch {
+		case j == len(b.hashes) || (i < len(a.hashes) && a.hashes[i] < b.hashes[j]):
+			idx.hashes = append(idx.hashes, a.hashes[i])
+			idx.occs = append(idx.occs, a.occs[a.offsets[i]:a.offsets[i+1]]...)
+			i++
+		case i == len(a.hashes) || b.hashes[j] < a.hashes[i]:
+			idx.hashes = append(idx.hashes, b.hashes[j])
+			appendB(j)
+			j++
+		default: // equal
+			idx.hashes = append(idx.hashes, a.hashes[i])
+			idx.occs = append(idx.occs, a.occs[a.offsets[i]:a.offsets[i+1]]...)
+			appendB(j)
+			i++
+			j++
+		}
+	}
+	idx.offsets = append(idx.offsets, uint64(len(idx.occs)))
+
+	idx.applyRepeatFilter(&a.Filter)
+	return idx, nil
+}
+
+// AddSequences adds new reference sequences to an index without
+// re-reading existing ones, and returns the updated index.
+// New sequences get reference IDs following the existing ones.
+// The original index is unchanged, a memory-mapped one can be closed afterwards.
+func (idx *Index) AddSequences(names []string, seqs [][]byte) (*Index, error) {
+	if len(names) != len(seqs) {
+		return nil, fmt.Errorf("strobemers: unequal numbers of names (%d) and sequences (%d)", len(names), len(seqs))
+	}
+
+	b, err := NewIndexBuilder(&idx.Params)
+	if err != nil {
+		return nil, err
+	}
+	for i, seq := range seqs {
+		if err = b.Add(names[i], seq); err != nil {
+			return nil, err
+		}
+	}
+	other := b.Build()
+	other.Strand = idx.Strand
+	other.Filter = idx.Filter // repetitive strobemers are flagged after merging
+
+	return MergeIndexes(idx, other)
+}
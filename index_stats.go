@@ -0,0 +1,135 @@
+package strobemers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// IndexStats contains statistics of an index.
+type IndexStats struct {
+	Method string `json:"method"` // e.g., RandStrobes(2,15,20,30)
+
+	NumRefs        int     `json:"nRefs"`
+	NumHashes      int     `json:"nHashes"`     // distinct strobemers
+	NumOccurrences int     `json:"nOccs"`       // all strobemers
+	NumUnique      int     `json:"nUnique"`     // strobemers occurring once
+	FracUnique     float64 `json:"fracUnique"`  // NumUnique / NumHashes
+	NumRepetitive  int     `json:"nRepetitive"` // strobemers flagged as repetitive
+	MeanSpan       float64 `json:"meanSpan"`    // mean strobemer span, from the first base of m1 to the last base of the last strobe
+	MaxSpan        int     `json:"maxSpan"`     // maximum strobemer span
+
+	Histogram []OccurrenceBin `json:"histogram"` // histogram of occurrence counts
+	Refs      []RefStats      `json:"refs"`
+}
+
+// OccurrenceBin is a bin of the occurrence histogram.
+type OccurrenceBin struct {
+	Occurrences int `json:"occs"`    // number of occurrences of a strobemer
+	Hashes      int `json:"nHashes"` // number of strobemers with that many occurrences
+}
+
+// RefStats contains statistics of a reference sequence.
+type RefStats struct {
+	Name    string  `json:"name"`
+	Length  int     `json:"length"`
+	Seeds   int     `json:"nSeeds"`
+	Density float64 `json:"density"` // seeds per base
+}
+
+// Stats computes statistics of the index.
+func (idx *Index) Stats() *IndexStats {
+	s := &IndexStats{
+		Method:         idx.Params.String(),
+		NumRefs:        idx.NumRefs(),
+		NumHashes:      idx.NumHashes(),
+		NumOccurrences: idx.NumOccurrences(),
+		NumRepetitive:  idx.NumRepetitive(),
+	}
+
+	// histogram
+	counts := make(map[int]int, 64)
+	var n int
+	for i := range idx.hashes {
+		n = int(idx.offsets[i+1] - idx.offsets[i])
+		counts[n]++
+	}
+	s.NumUnique = counts[1]
+	if s.NumHashes > 0 {
+		s.FracUnique = float64(s.NumUnique) / float64(s.NumHashes)
+	}
+	s.Histogram = make([]OccurrenceBin, 0, len(counts))
+	for n, c := range counts {
+		s.Histogram = append(s.Histogram, OccurrenceBin{Occurrences: n, Hashes: c})
+	}
+	sort.Slice(s.Histogram, func(i, j int) bool {
+		return s.Histogram[i].Occurrences < s.Histogram[j].Occurrences
+	})
+
+	// seeds and spans
+	seeds := make([]int, idx.NumRefs())
+	last := idx.Params.N - 1
+	var span, sumSpan int
+	for i := range idx.occs {
+		occ := &idx.occs[i]
+		seeds[occ.Ref]++
+
+		span = int(occ.Pos[last]) + idx.Params.L - int(occ.Pos[0])
+		sumSpan += span
+		if span > s.MaxSpan {
+			s.MaxSpan = span
+		}
+	}
+	if len(idx.occs) > 0 {
+		s.MeanSpan = float64(sumSpan) / float64(len(idx.occs))
+	}
+
+	s.Refs = make([]RefStats, idx.NumRefs())
+	for i, name := range idx.RefNames {
+		s.Refs[i] = RefStats{Name: name, Length: idx.RefLens[i], Seeds: seeds[i]}
+		if idx.RefLens[i] > 0 {
+			s.Refs[i].Density = float64(seeds[i]) / float64(idx.RefLens[i])
+		}
+	}
+
+	return s
+}
+
+// WriteTSV writes the summary in a tab-delimited table with a header line.
+func (s *IndexStats) WriteTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "method\tnRefs\tnHashes\tnOccs\tnUnique\tfracUnique\tnRepetitive\tmeanSpan\tmaxSpan\n")
+	fmt.Fprintf(bw, "%s\t%d\t%d\t%d\t%d\t%.4f\t%d\t%.2f\t%d\n",
+		s.Method, s.NumRefs, s.NumHashes, s.NumOccurrences, s.NumUnique,
+		s.FracUnique, s.NumRepetitive, s.MeanSpan, s.MaxSpan)
+	return bw.Flush()
+}
+
+// WriteHistogramTSV writes the histogram of occurrence counts in a tab-delimited table.
+func (s *IndexStats) WriteHistogramTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "occs\tnHashes\n")
+	for _, b := range s.Histogram {
+		fmt.Fprintf(bw, "%d\t%d\n", b.Occurrences, b.Hashes)
+	}
+	return bw.Flush()
+}
+
+// WriteRefsTSV writes the seed density of each reference in a tab-delimited table.
+func (s *IndexStats) WriteRefsTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ref\tlength\tnSeeds\tdensity\n")
+	for _, r := range s.Refs {
+		fmt.Fprintf(bw, "%s\t%d\t%d\t%.4f\n", r.Name, r.Length, r.Seeds, r.Density)
+	}
+	return bw.Flush()
+}
+
+// WriteJSON writes all statistics in JSON format.
+func (s *IndexStats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,54 @@
+package strobemers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIndexStats(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n3, _l3, _w_min, _w_max)
+	idx := buildTestIndex(t, p)
+	s := idx.Stats()
+
+	var nHashes, nOccs int
+	for _, b := range s.Histogram {
+		nHashes += b.Hashes
+		nOccs += b.Hashes * b.Occurrences
+	}
+	if nHashes != idx.NumHashes() || nOccs != idx.NumOccurrences() {
+		t.Errorf("histogram mismatch: %d/%d, %d/%d", nHashes, idx.NumHashes(), nOccs, idx.NumOccurrences())
+	}
+
+	var nSeeds int
+	for _, r := range s.Refs {
+		nSeeds += r.Seeds
+	}
+	if nSeeds != idx.NumOccurrences() {
+		t.Errorf("number of seeds mismatch: %d != %d", nSeeds, idx.NumOccurrences())
+	}
+	if s.MaxSpan < p.N*p.L || s.MeanSpan > float64(s.MaxSpan) {
+		t.Errorf("unexpected spans: mean %.2f, max %d", s.MeanSpan, s.MaxSpan)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := s.WriteTSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Errorf("unexpected TSV output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := s.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var s2 IndexStats
+	if err := json.Unmarshal(buf.Bytes(), &s2); err != nil {
+		t.Fatal(err)
+	}
+	if s2.NumHashes != s.NumHashes || len(s2.Refs) != len(s.Refs) {
+		t.Errorf("JSON roundtrip mismatch")
+	}
+}
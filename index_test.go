@@ -0,0 +1,258 @@
+package strobemers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func buildTestIndex(t *testing.T, p *Params) *Index {
+	b, err := NewIndexBuilder(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range seqs {
+		if err = b.Add(fmt.Sprintf("seq%d", i), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = b.Add("short", []byte("ACGT")); err != nil {
+		t.Fatal(err)
+	}
+	return b.Build()
+}
+
+func TestIndexLookup(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	idx := buildTestIndex(t, p)
+
+	if idx.NumRefs() != len(seqs)+1 {
+		t.Errorf("unexpected number of references: %d", idx.NumRefs())
+	}
+
+	iter, err := NewIterator(&seqs[0], p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hash uint64
+	var ok, found bool
+	var n int
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+		n++
+
+		found = false
+		for _, occ := range idx.Lookup(hash) {
+			if occ.Ref == 0 && int(occ.Pos[0]) == iter.Index() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("strobemer at %d not found in index", iter.Index())
+		}
+	}
+	if n != idx.NumOccurrences() {
+		t.Errorf("number of occurrences mismatch: %d != %d", idx.NumOccurrences(), n)
+	}
+}
+
+func TestIndexIO(t *testing.T) {
+	p := NewParams(SchemeMinStrobes, _n3, _l3, _w_min, _w_max)
+	idx := buildTestIndex(t, p)
+
+	buf := &bytes.Buffer{}
+	if _, err := idx.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	idx2, err := ReadIndex(bytes.NewReader(buf.Bytes()), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIndexEqual(t, idx, idx2)
+
+	file := filepath.Join(t.TempDir(), "test.sidx")
+	if err = os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx3, err := LoadIndex(file, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIndexEqual(t, idx, idx3)
+	if err = idx3.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// mismatched parameters
+	p2 := *p
+	p2.WMax++
+	_, err = LoadIndex(file, &p2)
+	if !errors.Is(err, ErrParamsMismatch) {
+		t.Errorf("expected ErrParamsMismatch, got: %v", err)
+	}
+
+	// truncated file
+	if err = os.WriteFile(file, buf.Bytes()[:buf.Len()-1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadIndex(file, nil)
+	if !errors.Is(err, ErrInvalidIndexFile) {
+		t.Errorf("expected ErrInvalidIndexFile, got: %v", err)
+	}
+
+	// a huge number of hashes in the header, with and without the input size
+	counts := make([]byte, 16)
+	binary.LittleEndian.PutUint64(counts, uint64(len(idx.hashes)))
+	binary.LittleEndian.PutUint64(counts[8:], uint64(len(idx.occs)))
+	i := bytes.Index(buf.Bytes(), counts)
+	if i < 0 {
+		t.Fatal("array sizes not found")
+	}
+	data := append([]byte{}, buf.Bytes()...)
+	binary.LittleEndian.PutUint64(data[i:], 1<<39)
+	_, err = ReadIndex(bytes.NewReader(data), nil)
+	if !errors.Is(err, ErrInvalidIndexFile) {
+		t.Errorf("expected ErrInvalidIndexFile, got: %v", err)
+	}
+	_, err = ReadIndex(struct{ io.Reader }{bytes.NewReader(data)}, nil)
+	if !errors.Is(err, ErrInvalidIndexFile) {
+		t.Errorf("expected ErrInvalidIndexFile, got: %v", err)
+	}
+
+	// reading from a stream
+	idx4, err := ReadIndex(struct{ io.Reader }{bytes.NewReader(buf.Bytes())}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIndexEqual(t, idx, idx4)
+}
+
+func checkIndexEqual(t *testing.T, a, b *Index) {
+	if !a.Params.Equal(&b.Params) || a.Strand != b.Strand || a.Version != b.Version ||
+		a.Filter != b.Filter {
+		t.Errorf("header mismatch")
+	}
+	if !reflect.DeepEqual(a.RefNames, b.RefNames) || !reflect.DeepEqual(a.RefLens, b.RefLens) {
+		t.Errorf("references mismatch")
+	}
+	if !reflect.DeepEqual(a.hashes, b.hashes) ||
+		!reflect.DeepEqual(a.offsets, b.offsets) ||
+		!reflect.DeepEqual(a.flags, b.flags) ||
+		!reflect.DeepEqual(a.occs, b.occs) {
+		t.Errorf("arrays mismatch")
+	}
+}
+
+func TestIndexRepeatFilter(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	// a tandem repeat followed by a unique sequence
+	unit := seqs[0][:100]
+	seq := make([]byte, 0, 2100)
+	for i := 0; i < 10; i++ {
+		seq = append(seq, unit...)
+	}
+	seq = append(seq, seqs[0][100:]...)
+
+	build := func(f RepeatFilter) *Index {
+		b, err := NewIndexBuilder(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetRepeatFilter(f)
+		if err = b.Add("repeat", seq); err != nil {
+			t.Fatal(err)
+		}
+		return b.Build()
+	}
+
+	all := build(RepeatFilter{})
+	if all.NumRepetitive() != 0 {
+		t.Errorf("no strobemers should be flagged without filter")
+	}
+
+	hard := build(RepeatFilter{MaxOcc: 2})
+	soft := build(RepeatFilter{MaxOcc: 2, Soft: true})
+	if hard.NumHashes() >= all.NumHashes() {
+		t.Errorf("repetitive strobemers not removed")
+	}
+	if soft.NumHashes() != all.NumHashes() {
+		t.Errorf("soft filtering should keep all strobemers")
+	}
+	if soft.NumRepetitive() != all.NumHashes()-hard.NumHashes() {
+		t.Errorf("number of flagged strobemers mismatch: %d != %d",
+			soft.NumRepetitive(), all.NumHashes()-hard.NumHashes())
+	}
+	for i, h := range soft.hashes {
+		occs, rep := soft.LookupWithFlag(h)
+		if rep != (len(occs) > 2) {
+			t.Errorf("wrong flag for hash %d", i)
+		}
+		if !rep && len(hard.Lookup(h)) != len(occs) {
+			t.Errorf("unique strobemer removed by hard filter")
+		}
+	}
+
+	top := build(RepeatFilter{TopFrac: 0.2, Soft: true})
+	if top.NumRepetitive() == 0 || top.NumRepetitive() > top.NumHashes()/5 {
+		t.Errorf("unexpected number of masked strobemers: %d of %d",
+			top.NumRepetitive(), top.NumHashes())
+	}
+}
+
+func TestIndexMerge(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	half := len(seqs[0]) / 2
+	parts := [][]byte{seqs[0][:half], seqs[0][half:], seqs[0]}
+	names := []string{"a", "b", "c"}
+
+	b, err := NewIndexBuilder(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range parts {
+		if err = b.Add(names[i], s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all := b.Build()
+
+	b, _ = NewIndexBuilder(p)
+	b.Add(names[0], parts[0])
+	first := b.Build()
+
+	merged, err := first.AddSequences(names[1:], parts[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIndexEqual(t, all, merged)
+
+	// incompatible parameters
+	p2 := NewParams(SchemeMinStrobes, _n2, _l2, _w_min, _w_max)
+	b, _ = NewIndexBuilder(p2)
+	b.Add(names[0], parts[0])
+	_, err = MergeIndexes(first, b.Build())
+	if !errors.Is(err, ErrParamsMismatch) {
+		t.Errorf("expected ErrParamsMismatch, got: %v", err)
+	}
+
+	// hard-filtered index
+	b, _ = NewIndexBuilder(p)
+	b.SetRepeatFilter(RepeatFilter{MaxOcc: 10})
+	b.Add(names[0], parts[0])
+	_, err = b.Build().AddSequences(names[1:2], parts[1:2])
+	if err != ErrIndexFiltered {
+		t.Errorf("expected ErrIndexFiltered, got: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package strobemers
+
+import (
+	"math"
+)
+
+// MapOptions contains the parameters of read mapping.
+type MapOptions struct {
+	MaxOcc int // strobemers with more occurrences are skipped, 0 for no limit
+
+	Chain ChainOptions
+
+	// A chain overlapping a better one by at least this fraction of the
+	// shorter query interval is secondary, otherwise it's primary.
+	MaskLevel float64
+	// Minimum ratio of a secondary chain score to the primary one.
+	SecondaryRatio float64
+	// Maximum number of secondary mappings of each primary mapping.
+	MaxSecondary int
+}
+
+// DefaultMapOptions is the default MapOptions.
+var DefaultMapOptions = MapOptions{
+	MaxOcc: 500,
+
+	Chain: DefaultChainOptions,
+
+	MaskLevel:      0.5,
+	SecondaryRatio: 0.8,
+	MaxSecondary:   5,
+}
+
+// Mapping is an approximate mapping location of a query.
+// Positions are 0-based, and intervals are half-open.
+type Mapping struct {
+	QName  string
+	QLen   int
+	QStart int // on the positive strand of the query
+	QEnd   int
+
+	Rev bool // the query is mapped to the negative strand
+
+	Ref    uint32 // reference ID
+	RName  string
+	RLen   int
+	RStart int
+	REnd   int
+
+	Primary bool
+	MapQ    int
+	Score   float64
+
+	Anchors  int     // number of anchors
+	Matches  int     // number of query bases covered by strobes
+	Coverage float64 // Matches / QLen
+
+	Chain *Chain
+}
+
+// Mapper maps queries to an index. An Index can be shared by Mappers,
+// while a Mapper should not be used in multiple goroutines.
+type Mapper struct {
+	idx *Index
+	opt MapOptions
+
+	anchors []Anchor
+}
+
+// NewMapper creates a Mapper.
+func NewMapper(idx *Index, opt *MapOptions) *Mapper {
+	return &Mapper{
+		idx:     idx,
+		opt:     *opt,
+		anchors: make([]Anchor, 0, 1024),
+	}
+}
+
+// Index returns the index of the mapper.
+func (mp *Mapper) Index() *Index {
+	return mp.idx
+}
+
+// Map maps a query and returns primary mappings followed by their secondary ones.
+// An empty list is returned for unmapped queries.
+func (mp *Mapper) Map(name string, seq []byte) ([]*Mapping, error) {
+	var err error
+	mp.anchors, err = mp.idx.Query(seq, mp.opt.MaxOcc, mp.anchors)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mp.idx.Params
+	chains := ChainAnchors(mp.anchors, p.N, p.L, &mp.opt.Chain)
+	return mp.selectMappings(name, len(seq), chains), nil
+}
+
+// selectMappings chooses primary and secondary chains, chains should be
+// sorted in decreasing order of scores.
+func (mp *Mapper) selectMappings(name string, qlen int, chains []*Chain) []*Mapping {
+	mappings := make([]*Mapping, 0, len(chains))
+	if len(chains) == 0 {
+		return mappings
+	}
+
+	primaries := make([]*Mapping, 0, 2)
+	secondaries := make([][]*Mapping, 0, 2)
+	bestOthers := make([]float64, 0, 2) // score of the best overlapping chain of a primary
+
+	var m *Mapping
+	var isPrimary bool
+	var j int
+	for _, c := range chains {
+		m = mp.newMapping(name, qlen, c)
+
+		isPrimary = true
+		for j = range primaries {
+			if overlapFraction(m.QStart, m.QEnd, primaries[j].QStart, primaries[j].QEnd) >= mp.opt.MaskLevel {
+				isPrimary = false
+				break
+			}
+		}
+		if isPrimary {
+			m.Primary = true
+			primaries = append(primaries, m)
+			secondaries = append(secondaries, nil)
+			bestOthers = append(bestOthers, 0)
+			continue
+		}
+
+		if c.Score > bestOthers[j] {
+			bestOthers[j] = c.Score
+		}
+		if c.Score >= primaries[j].Score*mp.opt.SecondaryRatio && len(secondaries[j]) < mp.opt.MaxSecondary {
+			secondaries[j] = append(secondaries[j], m)
+		}
+	}
+
+	for i, m := range primaries {
+		m.MapQ = mapQ(m.Score, bestOthers[i], m.Anchors)
+		mappings = append(mappings, m)
+	}
+	for _, ms := range secondaries {
+		mappings = append(mappings, ms...)
+	}
+	return mappings
+}
+
+func (mp *Mapper) newMapping(name string, qlen int, c *Chain) *Mapping {
+	m := &Mapping{
+		QName:    name,
+		QLen:     qlen,
+		QStart:   c.QStart,
+		QEnd:     c.QEnd,
+		Rev:      c.Rev,
+		Ref:      c.Ref,
+		RName:    mp.idx.RefNames[c.Ref],
+		RLen:     mp.idx.RefLens[c.Ref],
+		RStart:   c.RStart,
+		REnd:     c.REnd,
+		Score:    c.Score,
+		Anchors:  len(c.Anchors),
+		Matches:  c.QCovered,
+		Coverage: float64(c.QCovered) / float64(qlen),
+		Chain:    c,
+	}
+	if c.Rev {
+		m.QStart, m.QEnd = qlen-c.QEnd, qlen-c.QStart
+	}
+	return m
+}
+
+// mapQ computes the mapping quality from the ratio of the scores of the best
+// and the second best chains, it's also reduced for chains with few anchors.
+func mapQ(best, second float64, anchors int) int {
+	if best <= 0 {
+		return 0
+	}
+	q := 60 * (1 - second/best)
+	if anchors < 10 {
+		q *= float64(anchors) / 10
+	}
+	return int(math.Max(0, math.Min(60, math.Round(q))))
+}
+
+// overlapFraction returns the overlapped length of two intervals divided
+// by the length of the shorter one.
+func overlapFraction(s1, e1, s2, e2 int) float64 {
+	s, e := s1, e1
+	if s2 > s {
+		s = s2
+	}
+	if e2 < e {
+		e = e2
+	}
+	if e <= s {
+		return 0
+	}
+	return float64(e-s) / float64(minInt(e1-s1, e2-s2))
+}
@@ -0,0 +1,75 @@
+package strobemers
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func randomSeq(r *rand.Rand, n int) []byte {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = bit2base[r.Intn(4)]
+	}
+	return s
+}
+
+func TestMapper(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	ref1 := randomSeq(r, 5000)
+	ref2 := randomSeq(r, 5000)
+	copy(ref2[3000:3500], ref1[1000:1500]) // a duplicated region
+
+	b, _ := NewIndexBuilder(p)
+	b.Add("ref1", ref1)
+	b.Add("ref2", ref2)
+	mp := NewMapper(b.Build(), &DefaultMapOptions)
+
+	// a unique read on the negative strand
+	read := ReverseComplement(ref1[2000:2300], nil)
+	mappings, err := mp.Map("read1", read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 1 {
+		t.Fatalf("expected 1 mapping, got %d", len(mappings))
+	}
+	m := mappings[0]
+	if m.RName != "ref1" || !m.Rev || !m.Primary || m.MapQ < 30 {
+		t.Errorf("unexpected mapping: %+v", m)
+	}
+	if m.RStart-2000 != m.QLen-m.QEnd || m.RStart < 2000 || m.REnd > 2300 {
+		t.Errorf("unexpected coordinates: query [%d, %d), ref [%d, %d)", m.QStart, m.QEnd, m.RStart, m.REnd)
+	}
+
+	// a read from the duplicated region
+	mappings, err = mp.Map("read2", ref1[1100:1400])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 2 || !mappings[0].Primary || mappings[1].Primary || mappings[0].MapQ != 0 {
+		t.Errorf("expected a primary mapping with mapq 0 and a secondary one")
+	}
+
+	buf := &bytes.Buffer{}
+	w := NewPAFWriter(buf)
+	for _, m = range mappings {
+		if err = w.Write(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if n := len(strings.Split(line, "\t")); n != 16 {
+			t.Errorf("unexpected number of PAF columns: %d", n)
+		}
+	}
+
+	// unmapped
+	mappings, err = mp.Map("read3", randomSeq(r, 300))
+	if err != nil || len(mappings) != 0 {
+		t.Errorf("random read should be unmapped")
+	}
+}
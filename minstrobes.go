@@ -21,6 +21,8 @@ type MinStrobes struct {
 	minlocs   []int    // locations of min hash
 	minhashes []uint64 // minhashes of window [i-w,i]
 
+	sminlocs []int // locations of min hash of syncmers
+
 	endHash int // position of the last l-mer
 	endIdx  int // position of the last m1
 
@@ -28,6 +30,16 @@ type MinStrobes struct {
 
 	prime uint64
 
+	hashFunc HashFunc
+
+	sampling                    Sampling
+	sampleW, syncmerS, syncmerT int
+	sampled                     []bool // l-mers sampled as first strobes, nil for all
+
+	strobeSyncmerS int
+	nextSyncmer    []int // position of the next syncmer, nil for scanning all l-mers
+	syncmerOnly    bool  // whether only syncmers are scanned in the current window
+
 	// shrink the last searching window for positions near the end of sequence.
 	shrinkWindow bool
 
@@ -75,6 +87,8 @@ func NewMinStrobes(seq *[]byte, n int, l int, wMin int, wMax int) (*MinStrobes,
 		shrinkWindow: true,
 
 		prime: defaultPrimeNumber,
+
+		hashFunc: HashNtHash,
 	}
 
 	var err error
@@ -104,6 +118,113 @@ func (ms *MinStrobes) SetWindowShrink(shrink bool) {
 	ms.shrinkWindow = shrink
 }
 
+// SetHashFunc sets the function computing strobemer hash values.
+// Default is HashNtHash.
+func (ms *MinStrobes) SetHashFunc(h HashFunc) error {
+	if h != HashNtHash && h != HashNtHashMixed {
+		return ErrUnknownHashFunc
+	}
+	ms.hashFunc = h
+	return nil
+}
+
+// SetSampling restricts first strobes to sampled l-mers, reducing the
+// density of strobemers. w is the window size of minimizers or the
+// modulus, s and t are the s-mer length and offset of syncmers, unused
+// values should be 0. Default is SamplingNone.
+func (ms *MinStrobes) SetSampling(method Sampling, w int, s int, t int) error {
+	err := checkSampling(method, ms.l, w, s, t)
+	if err != nil {
+		return err
+	}
+	sampled, err := sampleFirstStrobes(ms.seq, ms.hashes, ms.l, method, w, s, t)
+	if err != nil {
+		return err
+	}
+	ms.sampling, ms.sampleW, ms.syncmerS, ms.syncmerT = method, w, s, t
+	ms.sampled = sampled
+	return nil
+}
+
+// SetSyncmerStrobes restricts the selection of the second and third strobes
+// to closed syncmers with s-mers of length s, i.e., l-mers whose smallest
+// s-mer is at the start or the end. All l-mers in a window are scanned if
+// none of them is a syncmer. s = 0 switches it off, which is the default.
+func (ms *MinStrobes) SetSyncmerStrobes(s int) error {
+	if s < 0 || s > ms.l {
+		return ErrInvalidSampling
+	}
+	ms.strobeSyncmerS = s
+	ms.nextSyncmer = nil
+	ms.sminlocs = nil
+	if s == 0 {
+		return nil
+	}
+	isSyncmer, err := syncmers(ms.seq, ms.l, s, 0, true)
+	if err != nil {
+		return err
+	}
+	ms.nextSyncmer = nextSyncmers(isSyncmer)
+
+	hashes := make([]uint64, len(ms.hashes))
+	for i, h := range ms.hashes {
+		if isSyncmer[i] {
+			hashes[i] = h
+		} else {
+			hashes[i] = math.MaxUint64
+		}
+	}
+	ms.sminlocs, _ = computeMinHashes(hashes, ms.wMax-ms.wMin+1)
+	return nil
+}
+
+// first returns the first l-mer to scan in the window [s, e].
+func (ms *MinStrobes) first(s, e int) int {
+	ms.syncmerOnly = ms.nextSyncmer != nil && s <= e && ms.nextSyncmer[s] <= e
+	if ms.syncmerOnly {
+		return ms.nextSyncmer[s]
+	}
+	return s
+}
+
+// next returns the l-mer to scan after i.
+func (ms *MinStrobes) next(i int) int {
+	if ms.syncmerOnly {
+		return ms.nextSyncmer[i+1]
+	}
+	return i + 1
+}
+
+// windowMin returns the position of the smallest l-mer in the window ending
+// at e, which is a syncmer if possible.
+func (ms *MinStrobes) windowMin(e int) int {
+	if ms.sminlocs != nil && ms.nextSyncmer[ms.sminlocs[e]] == ms.sminlocs[e] {
+		return ms.sminlocs[e]
+	}
+	return ms.minlocs[e]
+}
+
+// Params returns the parameters of the iterator.
+func (ms *MinStrobes) Params() Params {
+	return Params{
+		Scheme: SchemeMinStrobes,
+		N:      ms.n,
+		L:      ms.l,
+		WMin:   ms.wMin,
+		WMax:   ms.wMax,
+		Prime:  ms.prime,
+		Shrink: ms.shrinkWindow,
+		Hash:   ms.hashFunc,
+
+		Sampling: ms.sampling,
+		SampleW:  ms.sampleW,
+		SyncmerS: ms.syncmerS,
+		SyncmerT: ms.syncmerT,
+
+		StrobeSyncmerS: ms.strobeSyncmerS,
+	}
+}
+
 // Index returns the current index (0-based) of strobemers
 func (ms *MinStrobes) Index() int {
 	return ms.idx - 1
@@ -116,15 +237,25 @@ func (ms *MinStrobes) Indexes() []int {
 
 // Next returns the next hash value of randstrobe
 func (ms *MinStrobes) Next() (uint64, bool) {
+	var hash uint64
+	var ok bool
+	if ms.sampled != nil { // skip unsampled first strobes
+		for ms.idx <= ms.endIdx && !ms.sampled[ms.idx] {
+			ms.idx++
+		}
+	}
 	switch ms.n {
 	case 2:
-		return ms.nextOrder2()
+		hash, ok = ms.nextOrder2()
 	case 3:
-		return ms.nextOrder3()
+		hash, ok = ms.nextOrder3()
 	default:
 	}
 
-	return 0, false
+	if ok && ms.hashFunc == HashNtHashMixed {
+		hash = hash64(hash)
+	}
+	return hash, ok
 }
 
 func (ms *MinStrobes) nextOrder2() (uint64, bool) {
@@ -134,6 +265,11 @@ func (ms *MinStrobes) nextOrder2() (uint64, bool) {
 
 	ms.wStart = ms.idx + ms.wMin
 	ms.wEnd = ms.idx + ms.wMax
+	// with sampling, stop at empty windows near the end of the sequence,
+	// instead of reusing the second strobe of the previous strobemer.
+	if ms.sampled != nil && ms.wStart > ms.endHash {
+		return 0, false
+	}
 
 	// for positions near the end of the sequence, shrink the window size from the right
 	if ms.wEnd > ms.endHash {
@@ -146,7 +282,7 @@ func (ms *MinStrobes) nextOrder2() (uint64, bool) {
 
 		ms.hash1 = ms.hashes[ms.idx]
 		ms.hash2 = math.MaxUint64
-		for ms.i = ms.wStart; ms.i <= ms.wEnd; ms.i++ {
+		for ms.i = ms.first(ms.wStart, ms.wEnd); ms.i <= ms.wEnd; ms.i = ms.next(ms.i) {
 			ms.hash = ms.hashes[ms.i]
 			if ms.hash < ms.hash2 {
 				ms.idx2 = ms.i
@@ -157,8 +293,8 @@ func (ms *MinStrobes) nextOrder2() (uint64, bool) {
 		ms.hash2 = ms.hash1/2 + ms.hashes[ms.idx2]/3
 	} else { // use precomputed min hashes
 		ms.hash1 = ms.hashes[ms.idx]
-		ms.idx2 = ms.minlocs[ms.wEnd]
-		ms.hash2 = ms.hash1/2 + ms.minhashes[ms.wEnd]/3
+		ms.idx2 = ms.windowMin(ms.wEnd)
+		ms.hash2 = ms.hash1/2 + ms.hashes[ms.idx2]/3
 	}
 
 	ms.idx++
@@ -181,8 +317,8 @@ func (ms *MinStrobes) nextOrder3() (uint64, bool) {
 
 	// use precomputed min hashes
 	ms.hash1 = ms.hashes[ms.idx]
-	ms.idx2 = ms.minlocs[ms.wEnd]
-	ms.hash2 = ms.hash1/3 + ms.minhashes[ms.wEnd]/4
+	ms.idx2 = ms.windowMin(ms.wEnd)
+	ms.hash2 = ms.hash1/3 + ms.hashes[ms.idx2]/4
 
 	// for positions near the end of the sequence, shrink the last window size from the right
 	if ms.w2End > ms.endHash {
@@ -192,7 +328,7 @@ func (ms *MinStrobes) nextOrder3() (uint64, bool) {
 		ms.w2End = ms.endHash
 
 		ms.hash3 = math.MaxUint64
-		for ms.i = ms.w2Start; ms.i <= ms.w2End; ms.i++ {
+		for ms.i = ms.first(ms.w2Start, ms.w2End); ms.i <= ms.w2End; ms.i = ms.next(ms.i) {
 			ms.hash = (ms.hash2 + ms.hashes[ms.i]) & ms.prime
 			if ms.hash < ms.hash3 {
 				ms.idx3 = ms.i
@@ -201,8 +337,8 @@ func (ms *MinStrobes) nextOrder3() (uint64, bool) {
 		}
 		ms.hash3 = ms.hash2 + ms.hashes[ms.idx3]/5
 	} else {
-		ms.idx3 = ms.minlocs[ms.w2End]
-		ms.hash3 = ms.hash2 + ms.minhashes[ms.w2End]/5
+		ms.idx3 = ms.windowMin(ms.w2End)
+		ms.hash3 = ms.hash2 + ms.hashes[ms.idx3]/5
 	}
 
 	// fmt.Printf("i:%d, window (%d-%d)\n", ms.idx, ms.wStart, ms.wEnd)
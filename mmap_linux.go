@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package strobemers
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps a whole file into memory in read-only mode.
+func mmapFile(file string) ([]byte, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, ErrInvalidIndexFile
+	}
+	if int64(int(size)) != size {
+		return nil, ErrInvalidIndexFile
+	}
+
+	return syscall.Mmap(int(fh.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmapFile(data []byte) error {
+	return syscall.Munmap(data)
+}
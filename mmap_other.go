@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package strobemers
+
+import (
+	"os"
+)
+
+// mmapFile reads the whole file into memory on platforms other than Linux.
+func mmapFile(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrInvalidIndexFile
+	}
+	return data, nil
+}
+
+func munmapFile(data []byte) error {
+	return nil
+}
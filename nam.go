@@ -0,0 +1,134 @@
+package strobemers
+
+import (
+	"sort"
+)
+
+// NAM is a non-overlapping approximate match, i.e., a maximal interval
+// merged from overlapping strobemer hits on the same diagonal, as in strobealign.
+//
+// Positions are 0-based and intervals are half-open. For NAMs on the
+// negative strand (Rev is true), query positions are on the reverse
+// complementary sequence of the query.
+type NAM struct {
+	Ref uint32
+	Rev bool
+
+	QStart, QEnd int
+	RStart, REnd int
+
+	Hits  int     // number of merged hits
+	Score float64 // number of hits weighted by the span
+}
+
+// Diagonal returns the offset between reference and query positions.
+func (n *NAM) Diagonal() int {
+	return n.RStart - n.QStart
+}
+
+type anchorsByDiagonal []Anchor
+
+func (l anchorsByDiagonal) Len() int { return len(l) }
+func (l anchorsByDiagonal) Less(i int, j int) bool {
+	a, b := &l[i], &l[j]
+	if a.Ref != b.Ref {
+		return a.Ref < b.Ref
+	}
+	if a.Rev != b.Rev {
+		return !a.Rev
+	}
+	da, db := a.RPos[0]-a.QPos[0], b.RPos[0]-b.QPos[0]
+	if da != db {
+		return da < db
+	}
+	return a.QPos[0] < b.QPos[0]
+}
+func (l anchorsByDiagonal) Swap(i int, j int) { l[i], l[j] = l[j], l[i] }
+
+// FindNAMs merges anchors into NAMs. Anchors on the same reference and strand
+// are merged if their diagonals differ by at most maxShift (0 for exact
+// diagonals, a small value tolerates indels between strobes), and their
+// intervals, spanning from the first to the last strobe, overlap.
+//
+// The score of a NAM is the number of hits multiplied by the length of the
+// shorter one of the query and reference intervals, as in strobealign.
+// NAMs are returned in decreasing order of scores. n is the strobemer
+// order and l is the strobe length. The anchors are sorted in place.
+func FindNAMs(anchors []Anchor, n int, l int, maxShift int) []*NAM {
+	if len(anchors) == 0 {
+		return nil
+	}
+	sort.Sort(anchorsByDiagonal(anchors))
+
+	nams := make([]*NAM, 0, 16)
+	open := make([]*NAM, 0, 8) // NAMs that could still be extended, of the current reference and strand
+
+	var qs, qe, rs, re, d, i int
+	var merged bool
+	var last *Anchor
+	for k := range anchors {
+		a := &anchors[k]
+		if last == nil || a.Ref != last.Ref || a.Rev != last.Rev {
+			nams = append(nams, open...)
+			open = open[:0]
+		}
+		last = a
+
+		qs, qe = anchorSpan(&a.QPos, n, l)
+		rs, re = anchorSpan(&a.RPos, n, l)
+		d = a.RPos[0] - a.QPos[0]
+
+		merged = false
+		for i = 0; i < len(open); i++ {
+			nam := open[i]
+			if d-nam.Diagonal() > maxShift {
+				// anchors are sorted by diagonals, it can't be extended any more
+				nams = append(nams, nam)
+				open = append(open[:i], open[i+1:]...)
+				i--
+				continue
+			}
+			if qs > nam.QEnd || rs > nam.REnd || qe < nam.QStart || re < nam.RStart {
+				continue
+			}
+			nam.QStart, nam.QEnd = minInt(nam.QStart, qs), maxInt(nam.QEnd, qe)
+			nam.RStart, nam.REnd = minInt(nam.RStart, rs), maxInt(nam.REnd, re)
+			nam.Hits++
+			merged = true
+			break
+		}
+		if !merged {
+			open = append(open, &NAM{
+				Ref:    a.Ref,
+				Rev:    a.Rev,
+				QStart: qs,
+				QEnd:   qe,
+				RStart: rs,
+				REnd:   re,
+				Hits:   1,
+			})
+		}
+	}
+	nams = append(nams, open...)
+
+	for _, nam := range nams {
+		nam.Score = float64(nam.Hits * minInt(nam.QEnd-nam.QStart, nam.REnd-nam.RStart))
+	}
+	sort.SliceStable(nams, func(i, j int) bool { return nams[i].Score > nams[j].Score })
+	return nams
+}
+
+// anchorSpan returns the interval from the first base of the first strobe
+// to the last base of the last strobe.
+func anchorSpan(pos *[3]int, n int, l int) (int, int) {
+	s, e := pos[0], pos[0]
+	for i := 1; i < n; i++ {
+		if pos[i] < s {
+			s = pos[i]
+		}
+		if pos[i] > e {
+			e = pos[i]
+		}
+	}
+	return s, e + l
+}
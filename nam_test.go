@@ -0,0 +1,59 @@
+package strobemers
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFindNAMs(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	p := NewParams(SchemeRandStrobes, _n3, _l3, _w_min, _w_max)
+	ref := randomSeq(r, 5000)
+
+	b, _ := NewIndexBuilder(p)
+	b.Add("ref", ref)
+	idx := b.Build()
+
+	read := make([]byte, 300)
+	copy(read, ref[2000:2300])
+	read[150] = cbases[read[150]]
+
+	for _, rev := range []bool{false, true} {
+		q := read
+		if rev {
+			q = ReverseComplement(read, nil)
+		}
+		anchors, err := idx.Query(q, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nams := FindNAMs(anchors, p.N, p.L, 0)
+		if len(nams) == 0 {
+			t.Fatalf("no NAMs found")
+		}
+		// strobes could jump over the substitution
+		if nams[0].QEnd-nams[0].QStart < 250 {
+			t.Errorf("the best NAM is too short: [%d, %d)", nams[0].QStart, nams[0].QEnd)
+		}
+
+		var hits int
+		for _, nam := range nams {
+			if nam.Rev != rev || nam.Ref != 0 {
+				t.Errorf("unexpected NAM: %+v", nam)
+			}
+			if nam.Diagonal() != 2000 && !rev {
+				t.Errorf("unexpected diagonal: %d", nam.Diagonal())
+			}
+			if nam.QEnd-nam.QStart != nam.REnd-nam.RStart {
+				t.Errorf("query and reference spans should be equal without indels")
+			}
+			hits += nam.Hits
+		}
+		if hits != len(anchors) {
+			t.Errorf("number of hits mismatch: %d != %d", hits, len(anchors))
+		}
+		if nams[0].Score < nams[len(nams)-1].Score {
+			t.Errorf("NAMs not sorted by scores")
+		}
+	}
+}
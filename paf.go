@@ -0,0 +1,53 @@
+package strobemers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// PAFWriter writes mappings in the PAF format (https://github.com/lh3/miniasm/blob/master/PAF.md).
+//
+// The residue matches (column 10) are the number of query bases covered
+// by strobes, and the alignment block length (column 11) is the longer
+// one of the query and reference intervals. Optional tags:
+//
+//	tp:A  type of mapping, P for primary and S for secondary
+//	cm:i  number of anchors
+//	s1:f  chain score
+//	cv:f  fraction of query bases covered by strobes
+type PAFWriter struct {
+	w *bufio.Writer
+}
+
+// NewPAFWriter creates a PAFWriter.
+func NewPAFWriter(w io.Writer) *PAFWriter {
+	return &PAFWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a mapping.
+func (w *PAFWriter) Write(m *Mapping) error {
+	strand := '+'
+	if m.Rev {
+		strand = '-'
+	}
+	tp := 'S'
+	if m.Primary {
+		tp = 'P'
+	}
+	blen := m.QEnd - m.QStart
+	if m.REnd-m.RStart > blen {
+		blen = m.REnd - m.RStart
+	}
+	_, err := fmt.Fprintf(w.w, "%s\t%d\t%d\t%d\t%c\t%s\t%d\t%d\t%d\t%d\t%d\t%d\ttp:A:%c\tcm:i:%d\ts1:f:%.1f\tcv:f:%.4f\n",
+		m.QName, m.QLen, m.QStart, m.QEnd, strand,
+		m.RName, m.RLen, m.RStart, m.REnd,
+		m.Matches, blen, m.MapQ,
+		tp, m.Anchors, m.Score, m.Coverage)
+	return err
+}
+
+// Flush writes buffered data to the underlying writer.
+func (w *PAFWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,357 @@
+package strobemers
+
+import (
+	"math"
+)
+
+// PairOptions contains the parameters of paired-end mapping.
+// Only the FR orientation (Illumina paired-end) is supported.
+type PairOptions struct {
+	// Prior of the insert size, used before enough pairs are observed.
+	InsertMean float64
+	InsertStd  float64
+	// Minimum number of observed insert sizes to replace the prior.
+	MinSamples int
+	// Maximum insert size of a proper pair, also the upper bound of observations.
+	MaxInsert int
+	// A pair is proper if its insert size is within this many standard deviations of the mean.
+	ProperStds float64
+	// Minimum mapping quality of both mates to learn the insert size from a pair.
+	MinLearnMapQ int
+
+	// Penalty of choosing two mates not forming a proper pair.
+	UnpairedPenalty int
+
+	// Rescue a mate in the window defined by the insert size near its partner.
+	Rescue bool
+	// Minimum score of a rescued mate, as a fraction of the perfect score.
+	RescueMinScore float64
+}
+
+// DefaultPairOptions is the default PairOptions.
+var DefaultPairOptions = PairOptions{
+	InsertMean:   300,
+	InsertStd:    100,
+	MinSamples:   20,
+	MaxInsert:    2000,
+	ProperStds:   4,
+	MinLearnMapQ: 20,
+
+	UnpairedPenalty: 34,
+
+	Rescue:         true,
+	RescueMinScore: 0.5,
+}
+
+// InsertSizeModel learns the distribution of insert sizes online
+// with Welford's algorithm.
+type InsertSizeModel struct {
+	opt PairOptions
+
+	n        int
+	mean, m2 float64
+}
+
+// NewInsertSizeModel creates an InsertSizeModel.
+func NewInsertSizeModel(opt *PairOptions) *InsertSizeModel {
+	return &InsertSizeModel{opt: *opt}
+}
+
+// Add adds an observed insert size. Values out of the range of a proper
+// pair under the current model are ignored.
+func (m *InsertSizeModel) Add(insert int) {
+	if insert <= 0 || insert > m.opt.MaxInsert {
+		return
+	}
+	if m.n >= m.opt.MinSamples && !m.IsProper(insert) {
+		return
+	}
+	m.n++
+	d := float64(insert) - m.mean
+	m.mean += d / float64(m.n)
+	m.m2 += d * (float64(insert) - m.mean)
+}
+
+// N returns the number of observations.
+func (m *InsertSizeModel) N() int {
+	return m.n
+}
+
+// Mean returns the mean insert size, the prior is returned if not enough
+// insert sizes are observed.
+func (m *InsertSizeModel) Mean() float64 {
+	if m.n < m.opt.MinSamples {
+		return m.opt.InsertMean
+	}
+	return m.mean
+}
+
+// Std returns the standard deviation of insert sizes, the prior is
+// returned if not enough insert sizes are observed.
+func (m *InsertSizeModel) Std() float64 {
+	if m.n < m.opt.MinSamples {
+		return m.opt.InsertStd
+	}
+	return math.Max(1, math.Sqrt(m.m2/float64(m.n-1)))
+}
+
+// IsProper tells whether an insert size fits the model.
+func (m *InsertSizeModel) IsProper(insert int) bool {
+	return insert > 0 && insert <= m.opt.MaxInsert &&
+		math.Abs(float64(insert)-m.Mean()) <= m.opt.ProperStds*m.Std()
+}
+
+// maxProperInsert returns the maximum insert size of a proper pair.
+func (m *InsertSizeModel) maxProperInsert() int {
+	return minInt(m.opt.MaxInsert, int(m.Mean()+m.opt.ProperStds*m.Std()))
+}
+
+// PairedAlignment is the result of a read pair. Unmapped mates are nil.
+type PairedAlignment struct {
+	Aln1, Aln2 *Alignment
+
+	Proper  bool
+	Insert  int     // insert size of proper pairs
+	Rescued [2]bool // whether mates are rescued
+}
+
+// PairedAligner maps and aligns paired-end reads, learning the insert size
+// online. A PairedAligner should not be used in multiple goroutines.
+type PairedAligner struct {
+	al  *Aligner
+	opt PairOptions
+
+	Model *InsertSizeModel
+}
+
+// NewPairedAligner creates a PairedAligner.
+func NewPairedAligner(al *Aligner, opt *PairOptions) *PairedAligner {
+	return &PairedAligner{
+		al:    al,
+		opt:   *opt,
+		Model: NewInsertSizeModel(opt),
+	}
+}
+
+// AlignPair aligns a read pair, choosing the best pair of alignments by
+// joint scores. A mate without alignments, or without alignments forming
+// a proper pair, is rescued near the best alignments of its partner.
+func (pa *PairedAligner) AlignPair(name string, seq1, seq2 []byte) (*PairedAlignment, error) {
+	alns1, err := pa.al.Align(name, seq1)
+	if err != nil {
+		return nil, err
+	}
+	alns2, err := pa.al.Align(name, seq2)
+	if err != nil {
+		return nil, err
+	}
+
+	p := pa.bestPair(alns1, alns2)
+
+	if pa.opt.Rescue && !p.Proper {
+		if len(alns1) > 0 {
+			if a := pa.rescue(alns1[0], name, seq2); a != nil {
+				alns2 = append(alns2, a)
+			}
+		}
+		if len(alns2) > 0 {
+			if a := pa.rescue(alns2[0], name, seq1); a != nil {
+				alns1 = append(alns1, a)
+			}
+		}
+		p = pa.bestPair(alns1, alns2)
+		p.Rescued[0] = p.Aln1 != nil && p.Aln1.Chain == nil
+		p.Rescued[1] = p.Aln2 != nil && p.Aln2.Chain == nil
+	}
+
+	if p.Proper && !p.Rescued[0] && !p.Rescued[1] &&
+		p.Aln1.MapQ >= pa.opt.MinLearnMapQ && p.Aln2.MapQ >= pa.opt.MinLearnMapQ {
+		pa.Model.Add(p.Insert)
+	}
+	return p, nil
+}
+
+// insertSize returns the insert size of two alignments in FR orientation,
+// or 0 if they are not.
+func insertSize(a, b *Alignment) int {
+	if a.Ref != b.Ref || a.Rev == b.Rev {
+		return 0
+	}
+	if a.Rev {
+		a, b = b, a
+	}
+	if b.AlnREnd <= a.AlnRStart {
+		return 0
+	}
+	return b.AlnREnd - a.AlnRStart
+}
+
+// bestPair chooses the best pair of alignments by joint scores.
+func (pa *PairedAligner) bestPair(alns1, alns2 []*Alignment) *PairedAlignment {
+	p := &PairedAlignment{}
+	best := math.MinInt64
+
+	// unpaired
+	var s1, s2 int
+	if len(alns1) > 0 {
+		p.Aln1 = alns1[0]
+		s1 = alns1[0].AlnScore
+	}
+	if len(alns2) > 0 {
+		p.Aln2 = alns2[0]
+		s2 = alns2[0].AlnScore
+	}
+	if p.Aln1 != nil && p.Aln2 != nil {
+		best = s1 + s2 - pa.opt.UnpairedPenalty
+	}
+
+	var insert, score int
+	for _, a := range alns1 {
+		for _, b := range alns2 {
+			insert = insertSize(a, b)
+			if !pa.Model.IsProper(insert) {
+				continue
+			}
+			score = a.AlnScore + b.AlnScore
+			if score > best {
+				best = score
+				p.Aln1, p.Aln2 = a, b
+				p.Proper, p.Insert = true, insert
+			}
+		}
+	}
+
+	// the chosen ones are reported as primary
+	if p.Aln1 != nil && !p.Aln1.Primary {
+		p.Aln1 = asPrimary(p.Aln1)
+	}
+	if p.Aln2 != nil && !p.Aln2.Primary {
+		p.Aln2 = asPrimary(p.Aln2)
+	}
+	return p
+}
+
+// asPrimary returns a primary copy of a secondary alignment chosen by
+// pairing. Its mapping quality is 0, as the location is ambiguous
+// without the mate.
+func asPrimary(a *Alignment) *Alignment {
+	b := *a
+	m := *a.Mapping
+	m.Primary = true
+	m.MapQ = 0
+	b.Mapping = &m
+	return &b
+}
+
+// rescue aligns a mate in the window defined by the insert size model
+// near its partner.
+func (pa *PairedAligner) rescue(partner *Alignment, name string, seq []byte) *Alignment {
+	idx := pa.al.mp.idx
+	ref := pa.al.refs[partner.Ref]
+	maxInsert := pa.Model.maxProperInsert()
+
+	q := seq
+	var ws, we int
+	if partner.Rev { // mate on the positive strand, upstream
+		ws, we = maxInt(0, partner.AlnREnd-maxInsert), partner.AlnREnd
+	} else { // mate on the negative strand, downstream
+		ws, we = partner.AlnRStart, minInt(len(ref), partner.AlnRStart+maxInsert)
+		q = ReverseComplement(seq, nil)
+	}
+	if we-ws < len(q)/2 {
+		return nil
+	}
+
+	sc := &pa.al.opt.AlignScoring
+	res := alignFit(q, ref[ws:we], sc)
+	if float64(res.score) < pa.opt.RescueMinScore*float64(sc.Match*len(q)) {
+		return nil
+	}
+
+	m := &Mapping{
+		QName:   name,
+		QLen:    len(seq),
+		QStart:  0,
+		QEnd:    len(seq),
+		Rev:     !partner.Rev,
+		Ref:     partner.Ref,
+		RName:   idx.RefNames[partner.Ref],
+		RLen:    idx.RefLens[partner.Ref],
+		RStart:  ws + res.rs,
+		REnd:    ws + res.re,
+		Primary: true,
+		MapQ:    partner.MapQ,
+		Score:   float64(res.score),
+	}
+	return &Alignment{
+		Mapping:   m,
+		AlnQStart: 0,
+		AlnQEnd:   len(q),
+		AlnRStart: m.RStart,
+		AlnREnd:   m.REnd,
+		Cigar:     res.cigar,
+		AlnScore:  res.score,
+		NM:        editDistance(res.cigar, q, ref[m.RStart:]),
+	}
+}
+
+// SAMRecords creates SAM records of both mates with pair flags, mate
+// information and template lengths. seq and qual are of the positive strands.
+func (p *PairedAlignment) SAMRecords(name string, seq1, qual1, seq2, qual2 []byte) [2]*SAMRecord {
+	var recs [2]*SAMRecord
+	alns := [2]*Alignment{p.Aln1, p.Aln2}
+	seqs := [2][]byte{seq1, seq2}
+	quals := [2][]byte{qual1, qual2}
+	for i, a := range alns {
+		if a != nil {
+			recs[i] = a.SAMRecord(seqs[i], quals[i])
+		} else {
+			recs[i] = UnmappedSAMRecord(name, seqs[i], quals[i])
+		}
+		recs[i].QName = name
+		recs[i].Flag |= SAMPaired
+		if i == 0 {
+			recs[i].Flag |= SAMFirstInPair
+		} else {
+			recs[i].Flag |= SAMSecondInPair
+		}
+		if p.Proper {
+			recs[i].Flag |= SAMProperPair
+		}
+		if p.Rescued[i] {
+			recs[i].Tags = append(recs[i].Tags, "XR:i:1")
+		}
+	}
+
+	for i, a := range alns {
+		rec, mate := recs[i], alns[1-i]
+		if mate == nil {
+			rec.Flag |= SAMMateUnmapped
+			if a != nil { // RNEXT and PNEXT of an unmapped mate are those of the mapped one
+				rec.RNext, rec.PNext = "=", rec.Pos
+			}
+			continue
+		}
+		if mate.Rev {
+			rec.Flag |= SAMMateReverse
+		}
+		if a == nil { // place the unmapped read at its mate
+			rec.RName, rec.Pos = mate.RName, mate.AlnRStart+1
+			rec.RNext, rec.PNext = "=", mate.AlnRStart+1
+			continue
+		}
+
+		rec.PNext = mate.AlnRStart + 1
+		if mate.Ref == a.Ref {
+			rec.RNext = "="
+			s, e := minInt(a.AlnRStart, mate.AlnRStart), maxInt(a.AlnREnd, mate.AlnREnd)
+			rec.TLen = e - s
+			if a.AlnRStart > mate.AlnRStart || (a.AlnRStart == mate.AlnRStart && i == 1) {
+				rec.TLen = -rec.TLen
+			}
+		} else {
+			rec.RNext = mate.RName
+		}
+	}
+	return recs
+}
@@ -0,0 +1,77 @@
+package strobemers
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPairedAligner(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	ref := randomSeq(r, 20000)
+
+	b, _ := NewIndexBuilder(p)
+	b.Add("ref", ref)
+	mp := NewMapper(b.Build(), &DefaultMapOptions)
+	al, err := NewAligner(mp, [][]byte{ref}, &DefaultAlignOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa := NewPairedAligner(al, &DefaultPairOptions)
+
+	readLen := 150
+	simulate := func(start, insert int) ([]byte, []byte) {
+		read1 := append([]byte{}, ref[start:start+readLen]...)
+		read2 := ReverseComplement(ref[start+insert-readLen:start+insert], nil)
+		return read1, read2
+	}
+
+	// learn the insert size
+	for i := 0; i < 50; i++ {
+		insert := 400 + r.Intn(41) - 20
+		read1, read2 := simulate(1000+i*300, insert)
+		pair, err := pa.AlignPair("pair", read1, read2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pair.Proper || pair.Insert != insert {
+			t.Fatalf("expected a proper pair with insert size %d, got %v %d", insert, pair.Proper, pair.Insert)
+		}
+	}
+	if pa.Model.N() < DefaultPairOptions.MinSamples || pa.Model.Mean() < 390 || pa.Model.Mean() > 410 {
+		t.Errorf("unexpected insert size model: n=%d, mean=%.2f", pa.Model.N(), pa.Model.Mean())
+	}
+
+	// rescue a mate without seed hits: mutate every 10th base
+	read1, read2 := simulate(3000, 400)
+	for i := 5; i < len(read2); i += 10 {
+		read2[i] = cbases[read2[i]]
+	}
+	pair, err := pa.AlignPair("pair", read1, read2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.Aln2 == nil || !pair.Rescued[1] || !pair.Proper || pair.Aln2.AlnRStart != 3250 {
+		t.Fatalf("mate not rescued: %+v", pair)
+	}
+
+	recs := pair.SAMRecords("pair", read1, nil, read2, nil)
+	f1, f2 := recs[0].Flag, recs[1].Flag
+	if f1 != SAMPaired|SAMProperPair|SAMFirstInPair|SAMMateReverse ||
+		f2 != SAMPaired|SAMProperPair|SAMSecondInPair|SAMReverse {
+		t.Errorf("unexpected flags: %d, %d", f1, f2)
+	}
+	if recs[0].TLen != 400 || recs[1].TLen != -400 || recs[0].PNext != recs[1].Pos || recs[1].RNext != "=" {
+		t.Errorf("unexpected mate information: %+v, %+v", recs[0], recs[1])
+	}
+
+	// unmapped mate
+	pair, err = pa.AlignPair("pair", read1, randomSeq(r, readLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs = pair.SAMRecords("pair", read1, nil, read2, nil)
+	if recs[0].Flag&SAMMateUnmapped == 0 || recs[1].Flag&SAMUnmapped == 0 || recs[1].Pos != recs[0].Pos {
+		t.Errorf("unexpected flags of unmapped mate: %d, %d", recs[0].Flag, recs[1].Flag)
+	}
+}
@@ -0,0 +1,234 @@
+package strobemers
+
+import (
+	"fmt"
+)
+
+// Scheme is the method of strobemer construction.
+type Scheme uint8
+
+const (
+	// SchemeMinStrobes is for MinStrobes.
+	SchemeMinStrobes Scheme = iota + 1
+	// SchemeRandStrobes is for RandStrobes.
+	SchemeRandStrobes
+)
+
+func (s Scheme) String() string {
+	switch s {
+	case SchemeMinStrobes:
+		return "MinStrobes"
+	case SchemeRandStrobes:
+		return "RandStrobes"
+	}
+	return fmt.Sprintf("Scheme(%d)", uint8(s))
+}
+
+// HashFunc is the function computing strobemer hash values.
+type HashFunc uint8
+
+const (
+	// HashNtHash combines canonical ntHash values of all strobes,
+	// e.g., h(m1)/2+h(m2)/3 for order 2.
+	HashNtHash HashFunc = iota + 1
+	// HashNtHashMixed mixes values of HashNtHash with the finalizer of
+	// MurmurHash3, so hash values are uniformly distributed in [0, 2^64),
+	// as required by sketches.
+	HashNtHashMixed
+)
+
+func (h HashFunc) String() string {
+	switch h {
+	case HashNtHash:
+		return "ntHash"
+	case HashNtHashMixed:
+		return "ntHash-mixed"
+	}
+	return fmt.Sprintf("HashFunc(%d)", uint8(h))
+}
+
+// Params contains all parameters that affect the strobemer hash values
+// and positions. Two iterators with equal Params produce comparable
+// strobemers.
+type Params struct {
+	Scheme Scheme
+
+	N    int // strobemer order
+	L    int // strobes length
+	WMin int // minimum window offset
+	WMax int // maximum window offset
+
+	Prime  uint64   // the mask q in (h(m)+h(mj)) & q, i.e., roundup(q) - 1
+	Shrink bool     // shrink the last window near the end of sequence
+	Hash   HashFunc // hash function
+
+	Sampling Sampling // sampling of first strobes, see Sampling
+	SampleW  int      // window size of minimizers, or the modulus
+	SyncmerS int      // s-mer length of syncmers
+	SyncmerT int      // offset of the smallest s-mer of open syncmers
+
+	// s-mer length of closed syncmers, among which the second and third
+	// strobes are selected, 0 for all l-mers.
+	StrobeSyncmerS int
+}
+
+// NewParams returns Params with default prime number, hash function,
+// and window shrinking switched on.
+func NewParams(scheme Scheme, n int, l int, wMin int, wMax int) *Params {
+	return &Params{
+		Scheme: scheme,
+		N:      n,
+		L:      l,
+		WMin:   wMin,
+		WMax:   wMax,
+		Prime:  defaultPrimeNumber,
+		Shrink: true,
+		Hash:   HashNtHash,
+	}
+}
+
+// Validate checks whether the parameters are legal.
+func (p *Params) Validate() error {
+	if p.Scheme != SchemeMinStrobes && p.Scheme != SchemeRandStrobes {
+		return ErrUnknownScheme
+	}
+	if p.N < 2 {
+		return ErrInvalidOrder
+	}
+	if p.N > 3 {
+		return ErrOrderNotSupported
+	}
+	if p.L < 1 {
+		return ErrStrobeLengthTooSmall
+	}
+	if !(p.WMin > 0 && p.WMax > 0 && p.WMin <= p.WMax) {
+		return ErrInvalidWindowOffsets
+	}
+	if p.Prime < 255 {
+		return ErrPrimeNumberTooSmall
+	}
+	if p.Hash != HashNtHash && p.Hash != HashNtHashMixed {
+		return ErrUnknownHashFunc
+	}
+	if p.StrobeSyncmerS < 0 || p.StrobeSyncmerS > p.L {
+		return ErrInvalidSampling
+	}
+	return checkSampling(p.Sampling, p.L, p.SampleW, p.SyncmerS, p.SyncmerT)
+}
+
+// Equal tells whether two Params produce the same strobemers.
+func (p *Params) Equal(q *Params) bool {
+	return *p == *q
+}
+
+// String returns a short description like RandStrobes(2,15,20,30),
+// followed by the sampling methods if any, e.g., +closed-syncmer(s=11).
+func (p *Params) String() string {
+	s := fmt.Sprintf("%s(%d,%d,%d,%d)", p.Scheme, p.N, p.L, p.WMin, p.WMax)
+	switch p.Sampling {
+	case SamplingNone:
+	case SamplingMinimizer, SamplingModulo:
+		s += fmt.Sprintf("+%s(w=%d)", p.Sampling, p.SampleW)
+	case SamplingOpenSyncmer:
+		s += fmt.Sprintf("+%s(s=%d,t=%d)", p.Sampling, p.SyncmerS, p.SyncmerT)
+	default:
+		s += fmt.Sprintf("+%s(s=%d)", p.Sampling, p.SyncmerS)
+	}
+	if p.StrobeSyncmerS > 0 {
+		s += fmt.Sprintf("+syncmer-strobes(s=%d)", p.StrobeSyncmerS)
+	}
+	return s
+}
+
+// Iterator is the common interface of MinStrobes and RandStrobes.
+type Iterator interface {
+	// Next returns the next hash value of strobemer.
+	Next() (uint64, bool)
+	// Index returns the current index (0-based) of strobemers.
+	Index() int
+	// Indexes returns current indexes (0-based) of strobes.
+	Indexes() []int
+	// Params returns the parameters of the iterator.
+	Params() Params
+}
+
+// NewIterator creates a strobemer iterator of the given parameters.
+func NewIterator(seq *[]byte, p *Params) (Iterator, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Scheme {
+	case SchemeMinStrobes:
+		ms, err := NewMinStrobes(seq, p.N, p.L, p.WMin, p.WMax)
+		if err != nil {
+			return nil, err
+		}
+		ms.prime = p.Prime
+		ms.shrinkWindow = p.Shrink
+		ms.hashFunc = p.Hash
+		if err = ms.SetSampling(p.Sampling, p.SampleW, p.SyncmerS, p.SyncmerT); err != nil {
+			return nil, err
+		}
+		if err = ms.SetSyncmerStrobes(p.StrobeSyncmerS); err != nil {
+			return nil, err
+		}
+		return ms, nil
+	case SchemeRandStrobes:
+		rs, err := NewRandStrobes(seq, p.N, p.L, p.WMin, p.WMax)
+		if err != nil {
+			return nil, err
+		}
+		rs.prime = p.Prime
+		rs.shrinkWindow = p.Shrink
+		rs.hashFunc = p.Hash
+		if err = rs.SetSampling(p.Sampling, p.SampleW, p.SyncmerS, p.SyncmerT); err != nil {
+			return nil, err
+		}
+		if err = rs.SetSyncmerStrobes(p.StrobeSyncmerS); err != nil {
+			return nil, err
+		}
+		return rs, nil
+	}
+	return nil, ErrUnknownScheme
+}
+
+// newIteratorIfLongEnough returns a nil Iterator and nil error for
+// sequences too short to produce any strobemer.
+func newIteratorIfLongEnough(seq *[]byte, p *Params) (Iterator, error) {
+	if len(*seq) < p.N*p.L {
+		return nil, nil
+	}
+	iter, err := NewIterator(seq, p)
+	if err == ErrSequenceTooShort {
+		return nil, nil
+	}
+	return iter, err
+}
+
+// addStrobemers calls add for every strobemer of seq, and of its reverse
+// complement if both is true. Sequences too short to produce any strobemer
+// are skipped.
+func addStrobemers(seq []byte, p *Params, both bool, add func(hash uint64)) error {
+	seqs := [][]byte{seq}
+	if both {
+		seqs = append(seqs, ReverseComplement(seq, nil))
+	}
+	var hash uint64
+	var ok bool
+	for i := range seqs {
+		iter, err := newIteratorIfLongEnough(&seqs[i], p)
+		if err != nil || iter == nil {
+			return err
+		}
+		for {
+			hash, ok = iter.Next()
+			if !ok {
+				break
+			}
+			add(hash)
+		}
+	}
+	return nil
+}
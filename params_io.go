@@ -0,0 +1,62 @@
+package strobemers
+
+// paramsHeader is the binary form of Params in sketch and set files.
+type paramsHeader struct {
+	Scheme uint8
+	Hash   uint8
+	Shrink uint8
+	Sample uint8
+	N      uint32
+	L      uint32
+	WMin   uint32
+	WMax   uint32
+	Prime  uint64
+
+	SampleW  uint32
+	SyncmerS uint32
+	SyncmerT uint32
+	StrobeS  uint32
+}
+
+func newParamsHeader(p *Params) paramsHeader {
+	var shrink uint8
+	if p.Shrink {
+		shrink = 1
+	}
+	return paramsHeader{
+		Scheme: uint8(p.Scheme),
+		Hash:   uint8(p.Hash),
+		Shrink: shrink,
+		N:      uint32(p.N),
+		L:      uint32(p.L),
+		WMin:   uint32(p.WMin),
+		WMax:   uint32(p.WMax),
+		Prime:  p.Prime,
+		Sample: uint8(p.Sampling),
+
+		SampleW:  uint32(p.SampleW),
+		SyncmerS: uint32(p.SyncmerS),
+		SyncmerT: uint32(p.SyncmerT),
+		StrobeS:  uint32(p.StrobeSyncmerS),
+	}
+}
+
+func (h *paramsHeader) params() Params {
+	return Params{
+		Scheme: Scheme(h.Scheme),
+		N:      int(h.N),
+		L:      int(h.L),
+		WMin:   int(h.WMin),
+		WMax:   int(h.WMax),
+		Prime:  h.Prime,
+		Shrink: h.Shrink != 0,
+		Hash:   HashFunc(h.Hash),
+
+		Sampling: Sampling(h.Sample),
+		SampleW:  int(h.SampleW),
+		SyncmerS: int(h.SyncmerS),
+		SyncmerT: int(h.SyncmerT),
+
+		StrobeSyncmerS: int(h.StrobeS),
+	}
+}
@@ -0,0 +1,81 @@
+package strobemers
+
+// Anchor is a strobemer match between a query and a reference.
+//
+// Positions are 0-based. For anchors on the negative strand (Rev is true),
+// query positions are on the reverse complementary sequence of the query,
+// so anchors on both strands are collinear with increasing positions.
+// Use QueryPosForward to convert them.
+type Anchor struct {
+	Ref  uint32 // reference ID
+	Rev  bool   // the reverse complement of the query matches the reference
+	Rep  bool   // the strobemer is flagged as repetitive in the index
+	QPos [3]int // positions of strobes on the query, QPos[2] is 0 for order 2
+	RPos [3]int // positions of strobes on the reference
+}
+
+// QueryPosForward converts a query position of an anchor on the negative
+// strand to the position of the same strobe on the positive strand.
+func QueryPosForward(pos int, qlen int, l int) int {
+	return qlen - pos - l
+}
+
+// Query computes strobemers of a query sequence on both strands with the
+// parameters of the index, and returns anchors with the reference.
+//
+// Strobemers with more than maxOcc occurrences are skipped, 0 for no limit.
+// The anchors are appended to buf[:0], so the memory can be reused across queries.
+// A query too short to produce any strobemer returns no anchors.
+func (idx *Index) Query(seq []byte, maxOcc int, buf []Anchor) ([]Anchor, error) {
+	anchors := buf[:0]
+	var err error
+
+	anchors, err = idx.query(seq, false, maxOcc, anchors)
+	if err != nil {
+		return anchors, err
+	}
+
+	rc := ReverseComplement(seq, nil)
+	return idx.query(rc, true, maxOcc, anchors)
+}
+
+func (idx *Index) query(seq []byte, rev bool, maxOcc int, anchors []Anchor) ([]Anchor, error) {
+	iter, err := newIteratorIfLongEnough(&seq, &idx.Params)
+	if err != nil || iter == nil {
+		return anchors, err
+	}
+
+	var hash uint64
+	var ok, rep bool
+	var locs []int
+	var occs []Occurrence
+	var i int
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+
+		i = idx.search(hash)
+		if i < 0 {
+			continue
+		}
+		occs = idx.occs[idx.offsets[i]:idx.offsets[i+1]]
+		if maxOcc > 0 && len(occs) > maxOcc {
+			continue
+		}
+		rep = idx.flags[i]&flagRepetitive > 0
+
+		locs = iter.Indexes()
+		for _, occ := range occs {
+			anchors = append(anchors, Anchor{
+				Ref:  occ.Ref,
+				Rev:  rev,
+				Rep:  rep,
+				QPos: [3]int{locs[0], locs[1], locs[2]},
+				RPos: [3]int{int(occ.Pos[0]), int(occ.Pos[1]), int(occ.Pos[2])},
+			})
+		}
+	}
+	return anchors, nil
+}
@@ -0,0 +1,47 @@
+package strobemers
+
+import (
+	"testing"
+)
+
+func TestIndexQuery(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	idx := buildTestIndex(t, p)
+
+	start, end := 200, 500
+	query := seqs[0][start:end]
+
+	check := func(anchors []Anchor, rev bool) {
+		var n int
+		for _, a := range anchors {
+			if a.Rev != rev || a.Ref != 0 {
+				continue
+			}
+			if a.RPos[0]-a.QPos[0] == start {
+				n++
+			}
+		}
+		// strobemers near the end of query may differ due to window shrinking
+		if n < (end-start)/2 {
+			t.Errorf("too few anchors on the expected diagonal: %d", n)
+		}
+	}
+
+	anchors, err := idx.Query(query, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(anchors, false)
+
+	rc := ReverseComplement(query, nil)
+	anchors, err = idx.Query(rc, 0, anchors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(anchors, true)
+
+	anchors, err = idx.Query([]byte("ACGT"), 0, anchors)
+	if err != nil || len(anchors) != 0 {
+		t.Errorf("short query should produce no anchors: %d, %v", len(anchors), err)
+	}
+}
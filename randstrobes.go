@@ -23,6 +23,16 @@ type RandStrobes struct {
 
 	prime uint64
 
+	hashFunc HashFunc
+
+	sampling                    Sampling
+	sampleW, syncmerS, syncmerT int
+	sampled                     []bool // l-mers sampled as first strobes, nil for all
+
+	strobeSyncmerS int
+	nextSyncmer    []int // position of the next syncmer, nil for scanning all l-mers
+	syncmerOnly    bool  // whether only syncmers are scanned in the current window
+
 	// shrink the last searching window for positions near the end of sequence.
 	shrinkWindow bool
 
@@ -70,6 +80,8 @@ func NewRandStrobes(seq *[]byte, n int, l int, wMin int, wMax int) (*RandStrobes
 		shrinkWindow: true,
 
 		prime: defaultPrimeNumber,
+
+		hashFunc: HashNtHash,
 	}
 
 	var err error
@@ -94,6 +106,93 @@ func (rs *RandStrobes) SetWindowShrink(shrink bool) {
 	rs.shrinkWindow = shrink
 }
 
+// SetHashFunc sets the function computing strobemer hash values.
+// Default is HashNtHash.
+func (rs *RandStrobes) SetHashFunc(h HashFunc) error {
+	if h != HashNtHash && h != HashNtHashMixed {
+		return ErrUnknownHashFunc
+	}
+	rs.hashFunc = h
+	return nil
+}
+
+// SetSampling restricts first strobes to sampled l-mers, reducing the
+// density of strobemers. w is the window size of minimizers or the
+// modulus, s and t are the s-mer length and offset of syncmers, unused
+// values should be 0. Default is SamplingNone.
+func (rs *RandStrobes) SetSampling(method Sampling, w int, s int, t int) error {
+	err := checkSampling(method, rs.l, w, s, t)
+	if err != nil {
+		return err
+	}
+	sampled, err := sampleFirstStrobes(rs.seq, rs.hashes, rs.l, method, w, s, t)
+	if err != nil {
+		return err
+	}
+	rs.sampling, rs.sampleW, rs.syncmerS, rs.syncmerT = method, w, s, t
+	rs.sampled = sampled
+	return nil
+}
+
+// SetSyncmerStrobes restricts the selection of the second and third strobes
+// to closed syncmers with s-mers of length s, i.e., l-mers whose smallest
+// s-mer is at the start or the end. All l-mers in a window are scanned if
+// none of them is a syncmer. s = 0 switches it off, which is the default.
+func (rs *RandStrobes) SetSyncmerStrobes(s int) error {
+	if s < 0 || s > rs.l {
+		return ErrInvalidSampling
+	}
+	rs.strobeSyncmerS = s
+	rs.nextSyncmer = nil
+	if s == 0 {
+		return nil
+	}
+	isSyncmer, err := syncmers(rs.seq, rs.l, s, 0, true)
+	if err != nil {
+		return err
+	}
+	rs.nextSyncmer = nextSyncmers(isSyncmer)
+	return nil
+}
+
+// first returns the first l-mer to scan in the window [s, e].
+func (rs *RandStrobes) first(s, e int) int {
+	rs.syncmerOnly = rs.nextSyncmer != nil && s <= e && rs.nextSyncmer[s] <= e
+	if rs.syncmerOnly {
+		return rs.nextSyncmer[s]
+	}
+	return s
+}
+
+// next returns the l-mer to scan after i.
+func (rs *RandStrobes) next(i int) int {
+	if rs.syncmerOnly {
+		return rs.nextSyncmer[i+1]
+	}
+	return i + 1
+}
+
+// Params returns the parameters of the iterator.
+func (rs *RandStrobes) Params() Params {
+	return Params{
+		Scheme: SchemeRandStrobes,
+		N:      rs.n,
+		L:      rs.l,
+		WMin:   rs.wMin,
+		WMax:   rs.wMax,
+		Prime:  rs.prime,
+		Shrink: rs.shrinkWindow,
+		Hash:   rs.hashFunc,
+
+		Sampling: rs.sampling,
+		SampleW:  rs.sampleW,
+		SyncmerS: rs.syncmerS,
+		SyncmerT: rs.syncmerT,
+
+		StrobeSyncmerS: rs.strobeSyncmerS,
+	}
+}
+
 // Index returns the current index (0-based) of strobemers
 func (rs *RandStrobes) Index() int {
 	return rs.idx - 1
@@ -106,15 +205,25 @@ func (rs *RandStrobes) Indexes() []int {
 
 // Next returns the next hash value of randstrobe
 func (rs *RandStrobes) Next() (uint64, bool) {
+	var hash uint64
+	var ok bool
+	if rs.sampled != nil { // skip unsampled first strobes
+		for rs.idx <= rs.endIdx && !rs.sampled[rs.idx] {
+			rs.idx++
+		}
+	}
 	switch rs.n {
 	case 2:
-		return rs.nextOrder2()
+		hash, ok = rs.nextOrder2()
 	case 3:
-		return rs.nextOrder3()
+		hash, ok = rs.nextOrder3()
 	default:
 	}
 
-	return 0, false
+	if ok && rs.hashFunc == HashNtHashMixed {
+		hash = hash64(hash)
+	}
+	return hash, ok
 }
 
 func (rs *RandStrobes) nextOrder2() (uint64, bool) {
@@ -124,6 +233,11 @@ func (rs *RandStrobes) nextOrder2() (uint64, bool) {
 
 	rs.wStart = rs.idx + rs.wMin
 	rs.wEnd = rs.idx + rs.wMax
+	// with sampling, stop at empty windows near the end of the sequence,
+	// instead of reusing the second strobe of the previous strobemer.
+	if rs.sampled != nil && rs.wStart > rs.endHash {
+		return 0, false
+	}
 
 	// for positions near the end of the sequence, shrink the window size from the right
 	if rs.wEnd > rs.endHash {
@@ -137,7 +251,7 @@ func (rs *RandStrobes) nextOrder2() (uint64, bool) {
 
 	rs.hash1 = rs.hashes[rs.idx]
 	rs.hash2 = math.MaxUint64
-	for rs.i = rs.wStart; rs.i <= rs.wEnd; rs.i++ {
+	for rs.i = rs.first(rs.wStart, rs.wEnd); rs.i <= rs.wEnd; rs.i = rs.next(rs.i) {
 		rs.hash = (rs.hash1 + rs.hashes[rs.i]) & rs.prime
 		if rs.hash < rs.hash2 {
 			rs.idx2 = rs.i
@@ -176,7 +290,7 @@ func (rs *RandStrobes) nextOrder3() (uint64, bool) {
 
 	rs.hash1 = rs.hashes[rs.idx]
 	rs.hash2 = math.MaxUint64
-	for rs.i = rs.wStart; rs.i <= rs.wEnd; rs.i++ {
+	for rs.i = rs.first(rs.wStart, rs.wEnd); rs.i <= rs.wEnd; rs.i = rs.next(rs.i) {
 		rs.hash = (rs.hash1 + rs.hashes[rs.i]) & rs.prime
 		if rs.hash < rs.hash2 {
 			rs.idx2 = rs.i
@@ -186,7 +300,7 @@ func (rs *RandStrobes) nextOrder3() (uint64, bool) {
 	rs.hash2 = rs.hash1/3 + rs.hashes[rs.idx2]/4
 
 	rs.hash3 = math.MaxUint64
-	for rs.i = rs.w2Start; rs.i <= rs.w2End; rs.i++ {
+	for rs.i = rs.first(rs.w2Start, rs.w2End); rs.i <= rs.w2End; rs.i = rs.next(rs.i) {
 		rs.hash = (rs.hash2 + rs.hashes[rs.i]) & rs.prime
 		if rs.hash < rs.hash3 {
 			rs.idx3 = rs.i
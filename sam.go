@@ -0,0 +1,134 @@
+package strobemers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// SAM flags
+const (
+	SAMPaired        = 0x1
+	SAMProperPair    = 0x2
+	SAMUnmapped      = 0x4
+	SAMMateUnmapped  = 0x8
+	SAMReverse       = 0x10
+	SAMMateReverse   = 0x20
+	SAMFirstInPair   = 0x40
+	SAMSecondInPair  = 0x80
+	SAMSecondary     = 0x100
+	SAMSupplementary = 0x800
+)
+
+// SAMRecord is an alignment line of SAM format.
+type SAMRecord struct {
+	QName string
+	Flag  int
+	RName string // "*" for unmapped
+	Pos   int    // 1-based, 0 for unmapped
+	MapQ  int
+	Cigar string
+	RNext string // "*" for unavailable, "=" for the same reference
+	PNext int
+	TLen  int
+	Seq   []byte
+	Qual  []byte // nil for unavailable
+	Tags  []string
+}
+
+// SAMRecord creates a SAM record of the alignment with NM and AS tags.
+// seq and qual are of the positive strand, qual can be nil.
+func (a *Alignment) SAMRecord(seq []byte, qual []byte) *SAMRecord {
+	rec := &SAMRecord{
+		QName: a.QName,
+		RName: a.RName,
+		Pos:   a.AlnRStart + 1,
+		MapQ:  a.MapQ,
+		Cigar: a.Cigar.String(),
+		RNext: "*",
+		Seq:   seq,
+		Qual:  qual,
+		Tags:  []string{"NM:i:" + strconv.Itoa(a.NM), "AS:i:" + strconv.Itoa(a.AlnScore)},
+	}
+	if !a.Primary {
+		rec.Flag |= SAMSecondary
+	}
+	if a.Rev {
+		rec.Flag |= SAMReverse
+		rec.Seq = ReverseComplement(seq, nil)
+		if qual != nil {
+			rec.Qual = reverseBytes(qual)
+		}
+	}
+	return rec
+}
+
+// UnmappedSAMRecord creates a SAM record of an unmapped query.
+func UnmappedSAMRecord(name string, seq []byte, qual []byte) *SAMRecord {
+	return &SAMRecord{
+		QName: name,
+		Flag:  SAMUnmapped,
+		RName: "*",
+		Cigar: "*",
+		RNext: "*",
+		Seq:   seq,
+		Qual:  qual,
+	}
+}
+
+// SAMWriter writes records in SAM format.
+type SAMWriter struct {
+	w *bufio.Writer
+}
+
+// NewSAMWriter creates a SAMWriter.
+func NewSAMWriter(w io.Writer) *SAMWriter {
+	return &SAMWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteHeader writes the header lines with reference names and lengths
+// of the index, and an optional @PG line if program is not empty.
+func (w *SAMWriter) WriteHeader(idx *Index, program string, cmdline string) error {
+	var err error
+	write := func(format string, a ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w.w, format, a...)
+		}
+	}
+	write("@HD\tVN:1.6\tSO:unsorted\n")
+	for i, name := range idx.RefNames {
+		write("@SQ\tSN:%s\tLN:%d\n", name, idx.RefLens[i])
+	}
+	if program != "" {
+		write("@PG\tID:%s\tPN:%s\tVN:%s\tCL:%s\n", program, program, Version, cmdline)
+	}
+	return err
+}
+
+// Write writes a record.
+func (w *SAMWriter) Write(rec *SAMRecord) error {
+	seq, qual := "*", "*"
+	if len(rec.Seq) > 0 {
+		seq = string(rec.Seq)
+	}
+	if len(rec.Qual) > 0 {
+		qual = string(rec.Qual)
+	}
+	_, err := fmt.Fprintf(w.w, "%s\t%d\t%s\t%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s",
+		rec.QName, rec.Flag, rec.RName, rec.Pos, rec.MapQ, rec.Cigar,
+		rec.RNext, rec.PNext, rec.TLen, seq, qual)
+	if err != nil {
+		return err
+	}
+	for _, tag := range rec.Tags {
+		w.w.WriteByte('\t')
+		w.w.WriteString(tag)
+	}
+	return w.w.WriteByte('\n')
+}
+
+// Flush writes buffered data to the underlying writer.
+func (w *SAMWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,140 @@
+package strobemers
+
+import "fmt"
+
+// Sampling is the method selecting positions of first strobes, which
+// reduces the density of strobemers. Strobemers are unchanged, only
+// those with unsampled first strobes are skipped, so sampled strobemers
+// of two sequences still match wherever their first strobes match.
+type Sampling uint8
+
+const (
+	// SamplingNone produces a strobemer at every position.
+	SamplingNone Sampling = iota
+	// SamplingMinimizer keeps first strobes being (w,l) minimizers, i.e.,
+	// l-mers with the smallest hash value in a window of w l-mers.
+	// The density is about 2/(w+1).
+	SamplingMinimizer
+	// SamplingOpenSyncmer keeps first strobes whose smallest s-mer is at
+	// the offset t. The density is about 1/(l-s+1).
+	SamplingOpenSyncmer
+	// SamplingClosedSyncmer keeps first strobes whose smallest s-mer is
+	// at the start or the end. The density is about 2/(l-s+1).
+	SamplingClosedSyncmer
+	// SamplingModulo keeps first strobes with (mixed) hash values
+	// divisible by w. The density is about 1/w.
+	SamplingModulo
+)
+
+func (s Sampling) String() string {
+	switch s {
+	case SamplingNone:
+		return "none"
+	case SamplingMinimizer:
+		return "minimizer"
+	case SamplingOpenSyncmer:
+		return "open-syncmer"
+	case SamplingClosedSyncmer:
+		return "closed-syncmer"
+	case SamplingModulo:
+		return "modulo"
+	}
+	return fmt.Sprintf("Sampling(%d)", uint8(s))
+}
+
+// checkSampling checks sampling parameters of strobes of length l.
+// w is the window size of minimizers or the modulus, s and t are the
+// s-mer length and offset of syncmers. Unused values must be 0.
+func checkSampling(method Sampling, l, w, s, t int) error {
+	switch method {
+	case SamplingNone:
+		if w != 0 || s != 0 || t != 0 {
+			return ErrInvalidSampling
+		}
+	case SamplingMinimizer, SamplingModulo:
+		if w < 1 || s != 0 || t != 0 {
+			return ErrInvalidSampling
+		}
+	case SamplingOpenSyncmer:
+		if w != 0 || s < 1 || s > l || t < 0 || t > l-s {
+			return ErrInvalidSampling
+		}
+	case SamplingClosedSyncmer:
+		if w != 0 || s < 1 || s > l || t != 0 {
+			return ErrInvalidSampling
+		}
+	default:
+		return ErrUnknownSampling
+	}
+	return nil
+}
+
+// sampleFirstStrobes returns whether each l-mer is sampled as a first
+// strobe, or nil for SamplingNone.
+func sampleFirstStrobes(seq *[]byte, hashes []uint64, l int,
+	method Sampling, w, s, t int) ([]bool, error) {
+	switch method {
+	case SamplingNone:
+		return nil, nil
+	case SamplingMinimizer:
+		sampled := make([]bool, len(hashes))
+		if w > len(hashes) {
+			w = len(hashes)
+		}
+		locs, _ := computeMinHashes(hashes, w)
+		for i := w - 1; i < len(hashes); i++ {
+			sampled[locs[i]] = true
+		}
+		return sampled, nil
+	case SamplingOpenSyncmer:
+		return syncmers(seq, l, s, t, false)
+	case SamplingClosedSyncmer:
+		return syncmers(seq, l, s, 0, true)
+	case SamplingModulo:
+		sampled := make([]bool, len(hashes))
+		m := uint64(w)
+		for i, h := range hashes {
+			sampled[i] = hash64(h)%m == 0
+		}
+		return sampled, nil
+	}
+	return nil, ErrUnknownSampling
+}
+
+// syncmers returns whether each l-mer is an open syncmer with the smallest
+// s-mer at the offset t, or a closed syncmer.
+func syncmers(seq *[]byte, l, s, t int, closed bool) ([]bool, error) {
+	smers, err := computeHashes(seq, s)
+	if err != nil {
+		return nil, err
+	}
+	w := l - s + 1 // number of s-mers in an l-mer
+	locs, _ := computeMinHashes(smers, w)
+
+	sampled := make([]bool, len(*seq)-l+1)
+	var loc int
+	for i := range sampled {
+		loc = locs[i+w-1] - i // offset of the smallest s-mer
+		if closed {
+			sampled[i] = loc == 0 || loc == w-1
+		} else {
+			sampled[i] = loc == t
+		}
+	}
+	return sampled, nil
+}
+
+// nextSyncmers returns the position of the first syncmer at or after each
+// l-mer, with an extra element of len(syncmers) as the sentinel.
+func nextSyncmers(syncmers []bool) []int {
+	next := make([]int, len(syncmers)+1)
+	next[len(syncmers)] = len(syncmers)
+	for i := len(syncmers) - 1; i >= 0; i-- {
+		if syncmers[i] {
+			next[i] = i
+		} else {
+			next[i] = next[i+1]
+		}
+	}
+	return next
+}
@@ -0,0 +1,217 @@
+package strobemers
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+type strobemerAt struct {
+	hash    uint64
+	indexes [3]int
+}
+
+func collectStrobemers(t *testing.T, seq []byte, p *Params) map[int]strobemerAt {
+	iter, err := NewIterator(&seq, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[int]strobemerAt, len(seq))
+	var hash uint64
+	var ok bool
+	var idxs []int
+	for {
+		hash, ok = iter.Next()
+		if !ok {
+			break
+		}
+		idxs = iter.Indexes()
+		if iter.Index() != idxs[0] {
+			t.Fatalf("inconsistent index: %d != %d", iter.Index(), idxs[0])
+		}
+		if _, ok = m[idxs[0]]; ok {
+			t.Fatalf("duplicated first strobe: %d", idxs[0])
+		}
+		m[idxs[0]] = strobemerAt{hash: hash, indexes: [3]int{idxs[0], idxs[1], idxs[2]}}
+	}
+	return m
+}
+
+func TestSampling(t *testing.T) {
+	r := rand.New(rand.NewSource(43))
+	seq := randomSeq(r, 100000)
+
+	type sampling struct {
+		method  Sampling
+		w, s, t int
+		density float64
+	}
+	samplings := []sampling{
+		{SamplingMinimizer, 9, 0, 0, 2.0 / 10},
+		{SamplingOpenSyncmer, 0, 11, 2, 1.0 / 5},
+		{SamplingClosedSyncmer, 0, 11, 0, 2.0 / 5},
+		{SamplingModulo, 8, 0, 0, 1.0 / 8},
+	}
+	for _, scheme := range []Scheme{SchemeMinStrobes, SchemeRandStrobes} {
+		for _, n := range []int{2, 3} {
+			p := NewParams(scheme, n, _l2, _w_min, _w_max)
+			all := collectStrobemers(t, seq, p)
+
+			for _, smp := range samplings {
+				q := *p
+				q.Sampling, q.SampleW, q.SyncmerS, q.SyncmerT = smp.method, smp.w, smp.s, smp.t
+				sampled := collectStrobemers(t, seq, &q)
+
+				// sampled strobemers are the same as those at the same positions
+				for i, s := range sampled {
+					if a, ok := all[i]; !ok || a != s {
+						t.Fatalf("%s: strobemer at %d differs from the unsampled one", q.String(), i)
+					}
+				}
+				density := float64(len(sampled)) / float64(len(all))
+				if math.Abs(density-smp.density)/smp.density > 0.15 {
+					t.Errorf("%s: unexpected density: %.3f, expected: %.3f", q.String(), density, smp.density)
+				}
+
+				iter, _ := NewIterator(&seq, &q)
+				if got := iter.Params(); got != q {
+					t.Errorf("%s: unexpected params: %+v", q.String(), got)
+				}
+			}
+		}
+	}
+}
+
+func TestSamplingParams(t *testing.T) {
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+	invalid := []Params{
+		{Sampling: SamplingNone, SampleW: 5},
+		{Sampling: SamplingMinimizer},
+		{Sampling: SamplingModulo, SampleW: 4, SyncmerS: 3},
+		{Sampling: SamplingOpenSyncmer, SyncmerS: _l2 + 1},
+		{Sampling: SamplingOpenSyncmer, SyncmerS: 11, SyncmerT: _l2 - 10},
+		{Sampling: SamplingClosedSyncmer},
+		{Sampling: Sampling(99)},
+	}
+	for _, s := range invalid {
+		q := *p
+		q.Sampling, q.SampleW, q.SyncmerS, q.SyncmerT = s.Sampling, s.SampleW, s.SyncmerS, s.SyncmerT
+		if err := q.Validate(); err == nil {
+			t.Errorf("invalid sampling parameters should be rejected: %+v", s)
+		}
+	}
+
+	p.Sampling, p.SyncmerS = SamplingClosedSyncmer, 11
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.String(); s != "RandStrobes(2,15,20,30)+closed-syncmer(s=11)" {
+		t.Errorf("unexpected description: %s", s)
+	}
+
+	// the index file keeps sampling parameters
+	idx := buildTestIndex(t, p)
+	buf := &bytes.Buffer{}
+	if _, err := idx.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	idx2, err := ReadIndex(bytes.NewReader(buf.Bytes()), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx2.Params.Equal(p) {
+		t.Errorf("unexpected params: %+v", idx2.Params)
+	}
+	q := *p
+	q.Sampling, q.SyncmerS = SamplingNone, 0
+	if _, err = ReadIndex(bytes.NewReader(buf.Bytes()), &q); err == nil {
+		t.Errorf("index with different sampling should be rejected")
+	}
+}
+
+func TestSyncmerStrobes(t *testing.T) {
+	r := rand.New(rand.NewSource(44))
+	seq := randomSeq(r, 50000)
+
+	for _, scheme := range []Scheme{SchemeMinStrobes, SchemeRandStrobes} {
+		for _, n := range []int{2, 3} {
+			for _, s := range []int{11, 3} { // the latter leaves some windows without syncmers
+				p := NewParams(scheme, n, _l2, _w_min, _w_max)
+				all := collectStrobemers(t, seq, p)
+				q := *p
+				q.StrobeSyncmerS = s
+				restricted := collectStrobemers(t, seq, &q)
+				if len(restricted) != len(all) {
+					t.Fatalf("%s: unexpected number of strobemers: %d != %d", q.String(), len(restricted), len(all))
+				}
+
+				isSyncmer, err := syncmers(&seq, q.L, s, 0, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hasSyncmer := func(start, end int) bool {
+					if end > len(isSyncmer)-1 {
+						end = len(isSyncmer) - 1
+					}
+					for i := start; i <= end; i++ {
+						if isSyncmer[i] {
+							return true
+						}
+					}
+					return false
+				}
+
+				var fallback int
+				for i, sm := range restricted {
+					if hasSyncmer(i+q.WMin, i+q.WMax) {
+						if !isSyncmer[sm.indexes[1]] {
+							t.Fatalf("%s: the second strobe at %d is not a syncmer", q.String(), sm.indexes[1])
+						}
+					} else {
+						fallback++
+						if n == 2 && sm != all[i] {
+							t.Fatalf("%s: strobemer at %d differs from the unrestricted one", q.String(), i)
+						}
+					}
+					if n == 3 && hasSyncmer(i+q.WMax+q.WMin, i+q.WMax<<1) && !isSyncmer[sm.indexes[2]] {
+						t.Fatalf("%s: the third strobe at %d is not a syncmer", q.String(), sm.indexes[2])
+					}
+				}
+				if s == 3 && fallback == 0 {
+					t.Errorf("%s: no windows without syncmers", q.String())
+				}
+			}
+		}
+	}
+}
+
+func TestSamplingEmptyWindow(t *testing.T) {
+	r := rand.New(rand.NewSource(43))
+	seq := randomSeq(r, 1000)
+
+	// wMin > l, so the windows of the last few first strobes are empty
+	for _, scheme := range []Scheme{SchemeMinStrobes, SchemeRandStrobes} {
+		p := NewParams(scheme, _n2, _l2, _w_min, _w_max)
+
+		// the default output is unchanged: a strobemer for every first strobe
+		// up to len(seq)-n*l, reusing the last second strobe for empty windows
+		all := collectStrobemers(t, seq, p)
+		if n := len(seq) - 2*_l2 + 1; len(all) != n {
+			t.Errorf("%s: unexpected number of strobemers: %d != %d", p.String(), len(all), n)
+		}
+
+		// while sampling stops at the first empty window
+		q := *p
+		q.Sampling, q.SampleW = SamplingModulo, 1
+		sampled := collectStrobemers(t, seq, &q)
+		if n := len(seq) - _l2 - _w_min + 1; len(sampled) != n {
+			t.Errorf("%s: unexpected number of strobemers: %d != %d", q.String(), len(sampled), n)
+		}
+		for i := range sampled {
+			if i+_w_min > len(seq)-_l2 {
+				t.Errorf("%s: strobemer at %d with an empty window", q.String(), i)
+			}
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package strobemers
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Set file format (little-endian), for sorted and deduplicated strobemer
+// hash values, similar to .unik files of unikmer:
+//
+//	magic          [8]byte   "STROBSET"
+//	format version uint32
+//	flags          uint32    bit 0: compressed
+//	scheme, hash, shrink, sampling 4 x uint8
+//	n, l, wMin, wMax               4 x uint32
+//	prime          uint64
+//	sampleW, syncmerS, syncmerT    3 x uint32
+//	strobe syncmer s               uint32
+//	hashes         uint64 each, or uvarint deltas if compressed, until EOF
+//
+// Hash values are in ascending order, so files can be merged and compared
+// in a streaming way.
+
+// SetFormatVersion is the version of the set file format.
+const SetFormatVersion uint32 = 1
+
+var setMagic = [8]byte{'S', 'T', 'R', 'O', 'B', 'S', 'E', 'T'}
+
+const setFlagCompressed uint32 = 1
+
+// SetWriter writes strobemer hash values in ascending order to a set file.
+type SetWriter struct {
+	Params     Params
+	Compressed bool
+
+	w     *bufio.Writer
+	buf   []byte
+	prev  uint64
+	n     int
+	first bool
+}
+
+// NewSetWriter creates a SetWriter and writes the header. With compression,
+// hash values are saved as uvarint deltas, which is compact for dense sets.
+func NewSetWriter(w io.Writer, p *Params, compressed bool) (*SetWriter, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	sw := &SetWriter{
+		Params:     *p,
+		Compressed: compressed,
+		w:          bufio.NewWriterSize(w, 1<<16),
+		buf:        make([]byte, binary.MaxVarintLen64),
+		first:      true,
+	}
+	var flags uint32
+	if compressed {
+		flags |= setFlagCompressed
+	}
+	var err error
+	write := func(data interface{}) {
+		if err == nil {
+			err = binary.Write(sw.w, binary.LittleEndian, data)
+		}
+	}
+	write(setMagic)
+	write(SetFormatVersion)
+	write(flags)
+	write(newParamsHeader(p))
+	return sw, err
+}
+
+// Write writes a hash value, which should not be smaller than the previous
+// one. Duplicated values are skipped.
+func (sw *SetWriter) Write(hash uint64) error {
+	if !sw.first {
+		if hash == sw.prev {
+			return nil
+		}
+		if hash < sw.prev {
+			return ErrUnsortedSet
+		}
+	}
+	var err error
+	if sw.Compressed {
+		n := binary.PutUvarint(sw.buf, hash-sw.prev)
+		_, err = sw.w.Write(sw.buf[:n])
+	} else {
+		binary.LittleEndian.PutUint64(sw.buf, hash)
+		_, err = sw.w.Write(sw.buf[:8])
+	}
+	sw.prev = hash
+	sw.first = false
+	sw.n++
+	return err
+}
+
+// Count returns the number of hash values written.
+func (sw *SetWriter) Count() int {
+	return sw.n
+}
+
+// Flush writes buffered data to the underlying writer.
+func (sw *SetWriter) Flush() error {
+	return sw.w.Flush()
+}
+
+// WriteSet sorts and deduplicates hash values in place, and writes them
+// as a set file. It returns the number of hash values written.
+func WriteSet(w io.Writer, p *Params, hashes []uint64, compressed bool) (int, error) {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	sw, err := NewSetWriter(w, p, compressed)
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range hashes {
+		if err = sw.Write(h); err != nil {
+			return sw.Count(), err
+		}
+	}
+	return sw.Count(), sw.Flush()
+}
+
+// SetReader reads hash values from a set file.
+type SetReader struct {
+	Params     Params
+	Compressed bool
+
+	r    *bufio.Reader
+	buf  []byte
+	prev uint64
+	n    int // number of hash values read
+}
+
+// NewSetReader reads the header of a set file.
+func NewSetReader(r io.Reader) (*SetReader, error) {
+	sr := &SetReader{
+		r:   bufio.NewReaderSize(r, 1<<16),
+		buf: make([]byte, 8),
+	}
+	var err error
+	read := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(sr.r, binary.LittleEndian, data)
+		}
+	}
+	var magic [8]byte
+	var version, flags uint32
+	var hdr paramsHeader
+	read(&magic)
+	read(&version)
+	read(&flags)
+	read(&hdr)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, setReadError(err)
+	}
+	if magic != setMagic {
+		return nil, fmt.Errorf("%w: bad magic number", ErrInvalidSetFile)
+	}
+	if version != SetFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version: %d", ErrInvalidSetFile, version)
+	}
+	sr.Params = hdr.params()
+	if err = sr.Params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSetFile, err)
+	}
+	sr.Compressed = flags&setFlagCompressed > 0
+	return sr, nil
+}
+
+// Read returns the next hash value, or io.EOF at the end.
+func (sr *SetReader) Read() (uint64, error) {
+	var hash uint64
+	if sr.Compressed {
+		d, err := binary.ReadUvarint(sr.r)
+		if err != nil {
+			return 0, setReadError(err)
+		}
+		hash = sr.prev + d
+	} else {
+		n, err := io.ReadFull(sr.r, sr.buf)
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			if n > 0 {
+				return 0, fmt.Errorf("%w: unexpected end of file", ErrInvalidSetFile)
+			}
+			return 0, err
+		}
+		hash = binary.LittleEndian.Uint64(sr.buf)
+	}
+	if sr.n > 0 && hash <= sr.prev {
+		return 0, fmt.Errorf("%w: hash values not sorted or deduplicated", ErrInvalidSetFile)
+	}
+	sr.prev = hash
+	sr.n++
+	return hash, nil
+}
+
+// ReadAll reads all remaining hash values.
+func (sr *SetReader) ReadAll() ([]uint64, error) {
+	hashes := make([]uint64, 0, 1024)
+	for {
+		h, err := sr.Read()
+		if err == io.EOF {
+			return hashes, nil
+		}
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, h)
+	}
+}
+
+func setReadError(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of file", ErrInvalidSetFile)
+	}
+	return err
+}
+
+// ------------------------------------------------------------------------
+// set operations
+
+// setCursor is the current hash value of the i-th reader.
+type setCursor struct {
+	hash uint64
+	i    int
+}
+
+type setCursors []setCursor
+
+func (h setCursors) Len() int { return len(h) }
+func (h setCursors) Less(i, j int) bool {
+	return h[i].hash < h[j].hash || (h[i].hash == h[j].hash && h[i].i < h[j].i)
+}
+func (h setCursors) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *setCursors) Push(x interface{}) { *h = append(*h, x.(setCursor)) }
+func (h *setCursors) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// mergeSets merges readers in a streaming way, and writes hash values for
+// which keep(number of readers containing it, whether the first reader
+// contains it) returns true.
+func mergeSets(w *SetWriter, readers []*SetReader, keep func(n int, first bool) bool) error {
+	for _, r := range readers {
+		if !r.Params.Equal(&w.Params) {
+			return ErrParamsMismatch
+		}
+	}
+
+	h := make(setCursors, 0, len(readers))
+	next := func(i int) error {
+		hash, err := readers[i].Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(&h, setCursor{hash: hash, i: i})
+		return nil
+	}
+	for i := range readers {
+		if err := next(i); err != nil {
+			return err
+		}
+	}
+
+	var c setCursor
+	var n int
+	var first bool
+	for len(h) > 0 {
+		c = heap.Pop(&h).(setCursor)
+		n, first = 1, c.i == 0
+		if err := next(c.i); err != nil {
+			return err
+		}
+		for len(h) > 0 && h[0].hash == c.hash {
+			if err := next(heap.Pop(&h).(setCursor).i); err != nil {
+				return err
+			}
+			n++
+		}
+		if keep(n, first) {
+			if err := w.Write(c.hash); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// UnionSets writes hash values in any of the readers, i.e., merging
+// multiple set files.
+func UnionSets(w *SetWriter, readers ...*SetReader) error {
+	return mergeSets(w, readers, func(n int, first bool) bool { return true })
+}
+
+// IntersectSets writes hash values in all readers.
+func IntersectSets(w *SetWriter, readers ...*SetReader) error {
+	m := len(readers)
+	return mergeSets(w, readers, func(n int, first bool) bool { return n == m })
+}
+
+// SubtractSets writes hash values in the reader a but none of others,
+// e.g., strobemers in genome A but not in genome B.
+func SubtractSets(w *SetWriter, a *SetReader, others ...*SetReader) error {
+	readers := append([]*SetReader{a}, others...)
+	return mergeSets(w, readers, func(n int, first bool) bool { return first && n == 1 })
+}
@@ -0,0 +1,157 @@
+package strobemers
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSetFile(t *testing.T) {
+	r := rand.New(rand.NewSource(48))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	hashes := make([]uint64, 10000)
+	for i := range hashes {
+		hashes[i] = r.Uint64() >> 20
+	}
+	hashes = append(hashes, hashes[:100]...) // duplicates
+	expected := sortedUnique(hashes)
+
+	for _, compressed := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		n, err := WriteSet(buf, p, append([]uint64{}, hashes...), compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(expected) {
+			t.Errorf("unexpected number of hash values: %d", n)
+		}
+		if compressed && buf.Len() > 8*len(expected) {
+			t.Errorf("compressed file too large: %d", buf.Len())
+		}
+
+		sr, err := NewSetReader(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sr.Params.Equal(p) || sr.Compressed != compressed {
+			t.Errorf("unexpected header: %+v", sr.Params)
+		}
+		got, err := sr.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("unexpected hash values")
+		}
+
+		sr, _ = NewSetReader(bytes.NewReader(buf.Bytes()[:buf.Len()-3]))
+		if _, err = sr.ReadAll(); !errors.Is(err, ErrInvalidSetFile) {
+			t.Errorf("truncated file should be rejected: %v", err)
+		}
+	}
+
+	sw, _ := NewSetWriter(&bytes.Buffer{}, p, false)
+	sw.Write(10)
+	if err := sw.Write(5); err != ErrUnsortedSet {
+		t.Errorf("unsorted values should be rejected")
+	}
+	if _, err := NewSetReader(bytes.NewReader([]byte("STROBSET"))); !errors.Is(err, ErrInvalidSetFile) {
+		t.Errorf("truncated header should be rejected: %v", err)
+	}
+}
+
+func sortedUnique(hashes []uint64) []uint64 {
+	m := make(map[uint64]struct{}, len(hashes))
+	for _, h := range hashes {
+		m[h] = struct{}{}
+	}
+	s := make([]uint64, 0, len(m))
+	for h := range m {
+		s = append(s, h)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s
+}
+
+func TestSetOperations(t *testing.T) {
+	r := rand.New(rand.NewSource(48))
+	p := NewParams(SchemeRandStrobes, _n2, _l2, _w_min, _w_max)
+
+	// sets of random values in [0, 3000)
+	sets := make([][]uint64, 3)
+	files := make([][]byte, 3)
+	for i := range sets {
+		for j := 0; j < 1500; j++ {
+			sets[i] = append(sets[i], uint64(r.Intn(3000)))
+		}
+		sets[i] = sortedUnique(sets[i])
+		buf := &bytes.Buffer{}
+		if _, err := WriteSet(buf, p, sets[i], i == 1); err != nil {
+			t.Fatal(err)
+		}
+		files[i] = buf.Bytes()
+	}
+	readers := func() []*SetReader {
+		rs := make([]*SetReader, len(files))
+		for i, f := range files {
+			rs[i], _ = NewSetReader(bytes.NewReader(f))
+		}
+		return rs
+	}
+	counts := make(map[uint64]int, 3000)
+	inA := make(map[uint64]bool, 1500)
+	for i, s := range sets {
+		for _, h := range s {
+			counts[h]++
+			if i == 0 {
+				inA[h] = true
+			}
+		}
+	}
+	var union, inter, diff []uint64
+	for h, n := range counts {
+		union = append(union, h)
+		if n == 3 {
+			inter = append(inter, h)
+		}
+		if n == 1 && inA[h] {
+			diff = append(diff, h)
+		}
+	}
+
+	ops := []struct {
+		name     string
+		op       func(*SetWriter, []*SetReader) error
+		expected []uint64
+	}{
+		{"union", func(w *SetWriter, rs []*SetReader) error { return UnionSets(w, rs...) }, union},
+		{"intersection", func(w *SetWriter, rs []*SetReader) error { return IntersectSets(w, rs...) }, inter},
+		{"difference", func(w *SetWriter, rs []*SetReader) error { return SubtractSets(w, rs[0], rs[1:]...) }, diff},
+	}
+	for _, o := range ops {
+		buf := &bytes.Buffer{}
+		w, _ := NewSetWriter(buf, p, true)
+		if err := o.op(w, readers()); err != nil {
+			t.Fatal(err)
+		}
+		sr, _ := NewSetReader(bytes.NewReader(buf.Bytes()))
+		got, err := sr.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, sortedUnique(o.expected)) {
+			t.Errorf("%s: unexpected result of %d values, expected %d", o.name, len(got), len(o.expected))
+		}
+	}
+
+	q := *p
+	q.Hash = HashNtHashMixed
+	w, _ := NewSetWriter(&bytes.Buffer{}, &q, false)
+	if err := UnionSets(w, readers()...); err != ErrParamsMismatch {
+		t.Errorf("sets of different params should not be merged")
+	}
+}
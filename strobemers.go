@@ -1,3 +1,6 @@
 //Package strobemers is a Go implementation of the https://github.com/ksahlin/strobemers.
 
 package strobemers
+
+// Version is the version of this package, it's recorded in index files.
+const Version = "0.2.0"
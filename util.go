@@ -11,10 +11,20 @@ func roundup64(x uint64) uint64 {
 	x |= x >> 8
 	x |= x >> 16
 	x |= x >> 32
-	return (x | x>>64) + 1
+	return x + 1
 }
 
-// only used in tests
+// hash64 is the 64-bit finalizer of MurmurHash3, a bijection of uint64.
+func hash64(key uint64) uint64 {
+	key ^= key >> 33
+	key *= 0xff51afd7ed558ccd
+	key ^= key >> 33
+	key *= 0xc4ceb9fe1a85ec53
+	key ^= key >> 33
+	return key
+}
+
+// cbases is the table of complementary bases
 var cbases [256]byte = [256]byte{
 	'T', 'G', 'C', 'A', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N',
 	'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N',
@@ -33,3 +43,18 @@ var cbases [256]byte = [256]byte{
 	'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N',
 	'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N', 'N',
 }
+
+// ReverseComplement returns the reverse complementary sequence of seq,
+// the memory of buf is reused if it's large enough. A new slice is
+// allocated if buf is nil or too small. buf should not overlap seq.
+func ReverseComplement(seq []byte, buf []byte) []byte {
+	if cap(buf) < len(seq) {
+		buf = make([]byte, len(seq))
+	}
+	buf = buf[:len(seq)]
+	last := len(seq) - 1
+	for i, b := range seq {
+		buf[last-i] = cbases[b]
+	}
+	return buf
+}